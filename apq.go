@@ -0,0 +1,85 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// apqExtension is the name of the stats extension set by extension.AutomaticPersistedQuery.
+	apqExtension               = "APQ"
+	persistedQueryNotFoundCode = "PERSISTED_QUERY_NOT_FOUND"
+)
+
+// persistedQueryErrorMessages are the messages of the errors, without code, returned by extension.AutomaticPersistedQuery.
+var persistedQueryErrorMessages = []string{
+	"invalid APQ extension data",
+	"unsupported APQ version",
+	"provided APQ hash does not match query",
+}
+
+// Outcomes of the automatic persisted queries.
+const (
+	PersistedQueryHit      = "hit"
+	PersistedQueryMiss     = "miss"
+	PersistedQueryRegister = "register"
+)
+
+// persistedQuery returns the hash and the outcome of the automatic persisted query of the request:
+// a hit when only the hash was sent and found, a registration when the query was sent along with its hash,
+// and a miss when the hash was not found, in which case the hash is not known.
+// The outcome is empty if the request does not use a persisted query.
+func persistedQuery(oc *graphql.OperationContext, resp *graphql.Response) (string, string) {
+	if stats, ok := oc.Stats.GetExtension(apqExtension).(*extension.ApqStats); ok {
+		if stats.SentQuery {
+			return stats.Hash, PersistedQueryRegister
+		}
+		return stats.Hash, PersistedQueryHit
+	}
+	if resp != nil {
+		for _, err := range resp.Errors {
+			if errorCode(err) == persistedQueryNotFoundCode {
+				return "", PersistedQueryMiss
+			}
+		}
+	}
+	return "", ""
+}
+
+// persistedQueryAttributes returns the attributes describing the automatic persisted query of the request.
+func persistedQueryAttributes(oc *graphql.OperationContext, resp *graphql.Response) []attribute.KeyValue {
+	hash, outcome := persistedQuery(oc, resp)
+	if outcome == "" {
+		return nil
+	}
+	attrs := []attribute.KeyValue{PersistedQueryOutcome(outcome)}
+	if hash != "" {
+		attrs = append(attrs, PersistedQueryHash(hash))
+	}
+	return attrs
+}
+
+// recordPersistedQuery counts the automatic persisted query of the request by outcome.
+func (a Tracer) recordPersistedQuery(ctx context.Context, oc *graphql.OperationContext, resp *graphql.Response) {
+	if _, outcome := persistedQuery(oc, resp); outcome != "" {
+		a.instruments.persistedQueryCount.Add(ctx, 1, metric.WithAttributes(PersistedQueryOutcome(outcome)))
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestPersistedQuery(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New[string](10)})
+	srv.Use(Middleware(WithTracerProvider(provider), WithMeterProvider(meterProvider)))
+
+	query := "query NamedQuery { name }"
+	sum := sha256.Sum256([]byte(query))
+	hash := hex.EncodeToString(sum[:])
+
+	// the client first sends only the hash, then registers the query, then sends only the hash again
+	for _, sendQuery := range []bool{false, true, false} {
+		body := fmt.Sprintf(`{"extensions":{"persistedQuery":{"version":1,"sha256Hash":%q}}}`, hash)
+		if sendQuery {
+			body = fmt.Sprintf(`{"query":%q,"extensions":{"persistedQuery":{"version":1,"sha256Hash":%q}}}`, query, hash)
+		}
+		r := httptest.NewRequest("POST", "/foo", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		srv.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	var outcomes []string
+	for _, span := range endedSpans(spanRecorder) {
+		outcome, ok := spanAttribute(span, persistedQueryOutcomeKey)
+		if !ok {
+			continue
+		}
+		outcomes = append(outcomes, outcome.AsString())
+		spanHash, ok := spanAttribute(span, persistedQueryHashKey)
+		if outcome.AsString() == PersistedQueryMiss {
+			assert.False(t, ok)
+		} else {
+			assert.Equal(t, hash, spanHash.AsString())
+		}
+	}
+	assert.Equal(t, []string{PersistedQueryMiss, PersistedQueryRegister, PersistedQueryHit}, outcomes)
+
+	rm := collectMetrics(t, reader)
+	count, ok := findMetric(rm, persistedQueryMetric).Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, count.DataPoints, 3)
+	for _, dp := range count.DataPoints {
+		assert.Equal(t, int64(1), dp.Value)
+	}
+}
+
+func TestPersistedQueryNotUsed(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New[string](10)})
+	srv.Use(Middleware(WithMeterProvider(meterProvider)))
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+	rm := collectMetrics(t, reader)
+	assert.Nil(t, findMetric(rm, persistedQueryMetric).Data)
+}
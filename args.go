@@ -0,0 +1,173 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// normalizeArgs converts the args received by the resolver to plain values keyed by their GraphQL names,
+// so that they can be redacted by path and encoded as JSON: the generated code passes typed values,
+// such as structs for the input objects and pointers for the nullable scalars.
+// The input objects are walked along their schema type, and the scalars are converted to their JSON representation.
+// It also returns the names of the args whose input fields could not be resolved from the schema,
+// which are converted to their JSON representation as a whole.
+func (a Tracer) normalizeArgs(def *ast.FieldDefinition, args map[string]interface{}) (map[string]interface{}, []string) {
+	n := argsNormalizer{schema: a.schema.Load()}
+	normalized := make(map[string]interface{}, len(args))
+	var unresolved []string
+	for k, v := range args {
+		var argDef *ast.ArgumentDefinition
+		if def != nil {
+			argDef = def.Arguments.ForName(k)
+		}
+		if argDef == nil || n.schema == nil {
+			normalized[k] = jsonValue(v)
+			unresolved = append(unresolved, k)
+			continue
+		}
+		n.resolved = true
+		normalized[k] = n.normalize(reflect.ValueOf(v), argDef.Type)
+		if !n.resolved {
+			unresolved = append(unresolved, k)
+		}
+	}
+	return normalized, unresolved
+}
+
+// argsNormalizer converts typed values to plain values along their schema type.
+type argsNormalizer struct {
+	schema *ast.Schema
+	// resolved is unset when an input object could not be walked along its schema type.
+	resolved bool
+}
+
+func (n *argsNormalizer) normalize(v reflect.Value, typ *ast.Type) interface{} {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+
+	if typ.Elem != nil {
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			// a single value is coerced to a list of one value
+			return n.normalize(v, typ.Elem)
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = n.normalize(v.Index(i), typ.Elem)
+		}
+		return list
+	}
+
+	def := n.schema.Types[typ.NamedType]
+	if def == nil || def.Kind != ast.InputObject {
+		return jsonValue(v.Interface())
+	}
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		object := make(map[string]interface{}, v.Len())
+		for _, field := range def.Fields {
+			if fieldValue := v.MapIndex(reflect.ValueOf(field.Name).Convert(v.Type().Key())); fieldValue.IsValid() {
+				object[field.Name] = n.normalize(fieldValue, field.Type)
+			}
+		}
+		return object
+	case v.Kind() == reflect.Struct:
+		object := make(map[string]interface{}, len(def.Fields))
+		for _, field := range def.Fields {
+			if fieldValue, ok := boundField(v, field.Name); ok {
+				object[field.Name] = n.normalize(fieldValue, field.Type)
+			}
+		}
+		return object
+	default:
+		n.resolved = false
+		return jsonValue(v.Interface())
+	}
+}
+
+// boundField returns the field of the struct bound to the input field name, the way gqlgen binds the models:
+// by the json tag, or else by the name of the field, regardless of case and underscores,
+// or else in the embedded structs.
+func boundField(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && equalFieldName(tag, name) {
+			return v.Field(i), true
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.IsExported() && !field.Anonymous && equalFieldName(field.Name, name) {
+			return v.Field(i), true
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).Anonymous {
+			continue
+		}
+		embedded := v.Field(i)
+		if embedded.Kind() == reflect.Pointer {
+			if embedded.IsNil() {
+				continue
+			}
+			embedded = embedded.Elem()
+		}
+		if embedded.Kind() != reflect.Struct {
+			continue
+		}
+		if fieldValue, ok := boundField(embedded, name); ok {
+			return fieldValue, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// equalFieldName reports whether the names are equal regardless of case and underscores.
+func equalFieldName(source, target string) bool {
+	return strings.EqualFold(strings.ReplaceAll(source, "_", ""), strings.ReplaceAll(target, "_", ""))
+}
+
+// jsonValue converts the value to its JSON representation,
+// or to the name of its type if it cannot be encoded.
+func jsonValue(v interface{}) interface{} {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%T", v)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	// numbers are kept as written, rather than converted to float64
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Sprintf("%T", v)
+	}
+	return value
+}
@@ -15,8 +15,12 @@
 package otelgqlgen
 
 import (
+	"context"
+
 	"github.com/99designs/gqlgen/graphql"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -24,14 +28,83 @@ type FieldsPredicateFunc func(ctx *graphql.FieldContext) bool
 
 type SpanKindSelectorFunc func(operationName string) trace.SpanKind
 
+// ResponsePredicateFunc is the signature of the function
+// used to decide whether a response is selected.
+type ResponsePredicateFunc func(ctx context.Context, resp *graphql.Response) bool
+
+// OperationSpanKindSelectorFunc is the signature of the function
+// used to select the SpanKind of the operation span from the operation context.
+type OperationSpanKindSelectorFunc func(oc *graphql.OperationContext) trace.SpanKind
+
+// StatsExtensionMapperFunc is the signature of the function used to map the stats
+// set by a handler extension on the operation context to span attributes.
+type StatsExtensionMapperFunc func(stats interface{}) []attribute.KeyValue
+
+// statsExtension is a stats extension registered with WithStatsExtension.
+type statsExtension struct {
+	name   string
+	mapper StatsExtensionMapperFunc
+}
+
+// OperationSpanNameFormatterFunc is the signature of the function used to name the operation spans.
+type OperationSpanNameFormatterFunc func(ctx context.Context, oc *graphql.OperationContext) string
+
+// FieldSpanNameFormatterFunc is the signature of the function used to name the field spans.
+type FieldSpanNameFormatterFunc func(ctx context.Context, fc *graphql.FieldContext) string
+
+// OperationPredicateFunc is the signature of the function used to select operations.
+type OperationPredicateFunc func(oc *graphql.OperationContext) bool
+
+// AttributeConvention selects the naming of the operation span and its attributes.
+type AttributeConvention int
+
+const (
+	// LegacyConvention records the gql.request.* attributes and names the operation span
+	// after the operation name. It is the default.
+	LegacyConvention AttributeConvention = iota
+	// SemConvConvention records the graphql.* attributes defined by the OpenTelemetry
+	// semantic conventions and names the operation span "<operation type> <operation name>".
+	SemConvConvention
+	// DuplicateConvention records both the legacy and the semantic conventions attributes
+	// and keeps the legacy span name. It is meant to ease the migration to SemConvConvention.
+	DuplicateConvention
+)
+
 // config is used to configure the mongo tracer.
 type config struct {
 	TracerProvider             trace.TracerProvider
+	MeterProvider              metric.MeterProvider
+	Propagators                propagation.TextMapPropagator
+	ExtensionsPropagationKey   string
+	ResponseTraceIDKey         string
+	ResponseTraceParentKey     string
+	ResponseTracePredicate     ResponsePredicateFunc
+	RedactionPolicy            *RedactionPolicy
+	SensitiveDirective         string
+	ResolvedArgs               bool
 	Tracer                     trace.Tracer
 	ComplexityExtensionName    string
+	StatsExtensions            []statsExtension
+	ComplexityWarningThreshold float64
+	OperationSpanNameFormatter OperationSpanNameFormatterFunc
+	FieldSpanNameFormatter     FieldSpanNameFormatterFunc
+	ErrorClassifier            ErrorClassifierFunc
+	FieldErrorAttribution      bool
+	MaxRecordedErrors          int
+	StatusPolicy               StatusPolicyFunc
 	RequestVariablesBuilder    RequestVariablesBuilderFunc
 	ShouldCreateSpanFromFields FieldsPredicateFunc
 	SpanKindSelectorFunc       SpanKindSelectorFunc
+	OperationSpanKindSelector  OperationSpanKindSelectorFunc
+	ResolverMetrics            bool
+	ShouldMeasureFields        FieldsPredicateFunc
+	DisablePhaseSpans          bool
+	SubscriptionSpans          bool
+	AttributeConvention        AttributeConvention
+	QuerySignature             bool
+	QueryHashOnly              bool
+	MaxDocumentLength          int
+	ShouldOmitDocument         OperationPredicateFunc
 }
 
 // RequestVariablesBuilderFunc is the signature of the function
@@ -57,6 +130,98 @@ func WithTracerProvider(provider trace.TracerProvider) Option {
 	})
 }
 
+// WithMeterProvider specifies a meter provider to use for creating a meter.
+// If none is specified, the global provider is used.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.MeterProvider = provider
+	})
+}
+
+// WithPropagators specifies propagators to use for extracting trace context,
+// including from the websocket connection_init payloads.
+// If none is specified, the global propagators are used.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.Propagators = propagators
+	})
+}
+
+// WithExtensionsPropagation specifies a key of the request extensions from which the trace context
+// of the operation is extracted, e.g. "traceContext" for {"extensions":{"traceContext":{"traceparent":"00-..."}}}.
+// It is meant for clients that cannot send headers; when the extension is absent or invalid,
+// the trace context of the incoming request context is used.
+func WithExtensionsPropagation(key string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ExtensionsPropagationKey = key
+	})
+}
+
+// WithResponseTraceID adds the trace ID of the operation to the response extensions
+// under the given key, so that clients can report it. The key defaults to "traceId".
+// It is only added for sampled traces.
+func WithResponseTraceID(key string) Option {
+	return optionFunc(func(cfg *config) {
+		if key == "" {
+			key = "traceId"
+		}
+		cfg.ResponseTraceIDKey = key
+	})
+}
+
+// WithResponseTraceParent adds the W3C traceparent of the operation to the response extensions
+// under the given key. The key defaults to "traceparent". It is only added for sampled traces.
+func WithResponseTraceParent(key string) Option {
+	return optionFunc(func(cfg *config) {
+		if key == "" {
+			key = "traceparent"
+		}
+		cfg.ResponseTraceParentKey = key
+	})
+}
+
+// WithResponseTracePredicate restricts WithResponseTraceID and WithResponseTraceParent
+// to the responses matched by the predicate, e.g. OnlyErrorResponses or trusted clients.
+func WithResponseTracePredicate(predicate ResponsePredicateFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ResponseTracePredicate = predicate
+	})
+}
+
+// WithQuerySignature records a normalized signature of the query, with the literals stripped,
+// the aliases dropped, the selections sorted and the whitespace collapsed, along with its SHA-256 hash,
+// to group identical operations.
+func WithQuerySignature() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.QuerySignature = true
+	})
+}
+
+// WithQueryHashOnly records only the hash of the query signature, instead of the query and the signature,
+// to cut the span size.
+func WithQueryHashOnly() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.QuerySignature = true
+		cfg.QueryHashOnly = true
+	})
+}
+
+// WithMaxDocumentLength truncates the recorded query to at most maxLength bytes, followed by a marker,
+// and records the original length of the query. A zero or negative maxLength disables the truncation.
+func WithMaxDocumentLength(maxLength int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.MaxDocumentLength = maxLength
+	})
+}
+
+// WithoutDocument omits the query of the operations matching the predicate,
+// recording only its original length.
+func WithoutDocument(predicate OperationPredicateFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ShouldOmitDocument = predicate
+	})
+}
+
 // WithComplexityExtensionName specifies complexity extension name.
 func WithComplexityExtensionName(complexityExtensionName string) Option {
 	return optionFunc(func(cfg *config) {
@@ -64,6 +229,23 @@ func WithComplexityExtensionName(complexityExtensionName string) Option {
 	})
 }
 
+// WithComplexityWarningThreshold records a span event when the complexity of an operation
+// reaches the given percentage of the complexity limit, e.g. 80, to spot the clients nearing rejection.
+func WithComplexityWarningThreshold(percent float64) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ComplexityWarningThreshold = percent
+	})
+}
+
+// WithStatsExtension records the stats set by a handler extension under the given name,
+// i.e. oc.Stats.SetExtension(name, stats), as the operation span attributes returned by the mapper.
+// It can be used several times to record several extensions.
+func WithStatsExtension(name string, mapper StatsExtensionMapperFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.StatsExtensions = append(cfg.StatsExtensions, statsExtension{name: name, mapper: mapper})
+	})
+}
+
 // WithRequestVariablesAttributesBuilder allows specifying a custom function
 // to handle the building of the attributes for the variables.
 func WithRequestVariablesAttributesBuilder(builder RequestVariablesBuilderFunc) Option {
@@ -81,6 +263,84 @@ func WithoutVariables() Option {
 	})
 }
 
+// WithRedactionPolicy redacts the request variables matched by the policy before they are
+// passed to the request variables builder. The paths of the redacted values are recorded
+// in the gql.request.variables.redacted attribute.
+func WithRedactionPolicy(policy RedactionPolicy) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.RedactionPolicy = &policy
+	})
+}
+
+// WithSensitiveDirective redacts the values marked as sensitive in the schema by the given directive,
+// "sensitive" by default. The directive is honored on field arguments and input fields, for both the
+// resolver args and the request variables passed to them, and on variable definitions, e.g.
+//
+//	directive @sensitive on ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION | VARIABLE_DEFINITION
+func WithSensitiveDirective(name string) Option {
+	return optionFunc(func(cfg *config) {
+		if name == "" {
+			name = defaultSensitiveDirective
+		}
+		cfg.SensitiveDirective = name
+	})
+}
+
+// WithResolvedArgs records the resolver args as received by the resolver, after variables
+// substitution and defaulting, instead of as written in the query, e.g. "42" instead of "$id".
+// The values are encoded as JSON and redacted according to WithRedactionPolicy and WithSensitiveDirective.
+func WithResolvedArgs() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ResolvedArgs = true
+	})
+}
+
+// WithSpanNameFormatter specifies the functions naming the operation and field spans,
+// e.g. TopLevelFieldsSpanName. A nil formatter keeps the default names: the operation name,
+// or "nameless-operation" for anonymous operations, and "Object/field".
+// The span kind selectors still receive the default names.
+func WithSpanNameFormatter(operation OperationSpanNameFormatterFunc, field FieldSpanNameFormatterFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.OperationSpanNameFormatter = operation
+		cfg.FieldSpanNameFormatter = field
+	})
+}
+
+// WithErrorClassifier specifies the function deciding, for each GraphQL error of the operation and field spans,
+// whether it fails the span, is only recorded, or is ignored, e.g. to not fail the spans on client errors.
+// Unclassified errors fail the span. The classifiers can be combined with ChainErrorClassifiers.
+func WithErrorClassifier(classifier ErrorClassifierFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ErrorClassifier = classifier
+	})
+}
+
+// WithFieldErrorAttribution records each error only on the span of the field that produced it.
+// The operation span then carries the count of the errors and a summary status,
+// and only records the errors of the fields without a span.
+func WithFieldErrorAttribution() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.FieldErrorAttribution = true
+	})
+}
+
+// WithMaxRecordedErrors records at most maxErrors errors individually per span.
+// The error count still covers all the errors. A zero or negative maxErrors disables the cap.
+func WithMaxRecordedErrors(maxErrors int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.MaxRecordedErrors = maxErrors
+	})
+}
+
+// WithStatusPolicy specifies the function deciding the status code of the operation span
+// from the outcome of a response with failing errors, e.g. PartialSuccessStatusPolicy(codes.Unset)
+// to not fail the responses with both data and errors. Defaults to DefaultStatusPolicy.
+func WithStatusPolicy(policy StatusPolicyFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.StatusPolicy = policy
+	})
+}
+
 // WithCreateSpanFromFields allows specifying a custom function
 // to handle the creation or not of spans regarding the GraphQL context fields.
 func WithCreateSpanFromFields(predicate FieldsPredicateFunc) Option {
@@ -95,3 +355,47 @@ func WithSpanKindSelector(spanKindSelector SpanKindSelectorFunc) Option {
 		cfg.SpanKindSelectorFunc = spanKindSelector
 	})
 }
+
+// WithOperationSpanKindSelector allows specifying a custom function that defines the SpanKind
+// of the operation span based on the operation context, e.g. on the operation type.
+// It takes precedence over WithSpanKindSelector for the operation span.
+func WithOperationSpanKindSelector(spanKindSelector OperationSpanKindSelectorFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.OperationSpanKindSelector = spanKindSelector
+	})
+}
+
+// WithResolverMetrics enables the resolver duration histogram.
+// The predicate selects the fields to measure; if it is nil,
+// the predicate given to WithCreateSpanFromFields is used.
+func WithResolverMetrics(predicate FieldsPredicateFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ResolverMetrics = true
+		cfg.ShouldMeasureFields = predicate
+	})
+}
+
+// WithoutPhaseSpans disables the spans recorded for the read, parse and validation
+// phases of each operation.
+func WithoutPhaseSpans() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.DisablePhaseSpans = true
+	})
+}
+
+// WithSubscriptionSpans enables a single span covering the whole lifetime of each subscription,
+// instead of a separate operation span for each event. The events are recorded as span events
+// carrying their sequence number, payload size and errors.
+func WithSubscriptionSpans() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.SubscriptionSpans = true
+	})
+}
+
+// WithAttributeConvention specifies the convention used to name the operation span
+// and its attributes. LegacyConvention is used by default.
+func WithAttributeConvention(convention AttributeConvention) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.AttributeConvention = convention
+	})
+}
@@ -0,0 +1,200 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// ErrorClass decides how a GraphQL error is recorded on a span.
+type ErrorClass int
+
+const (
+	// ErrorClassUnclassified leaves the error to the next classifier. It is handled as ErrorClassFailure.
+	ErrorClassUnclassified ErrorClass = iota
+	// ErrorClassFailure records the error and sets the span status to Error, e.g. for server failures.
+	ErrorClassFailure
+	// ErrorClassRecorded only records the error, e.g. for client errors.
+	ErrorClassRecorded
+	// ErrorClassIgnored does not record the error.
+	ErrorClassIgnored
+)
+
+// ErrorClassifierFunc is the signature of the function used to classify the GraphQL errors.
+type ErrorClassifierFunc func(err *gqlerror.Error) ErrorClass
+
+// ClassifyErrorCodes classifies the errors with any of the given extensions codes, e.g. "NOT_FOUND".
+func ClassifyErrorCodes(class ErrorClass, codes ...string) ErrorClassifierFunc {
+	return func(err *gqlerror.Error) ErrorClass {
+		if slices.Contains(codes, errorCode(err)) {
+			return class
+		}
+		return ErrorClassUnclassified
+	}
+}
+
+// ClassifyErrorsAs classifies the errors wrapping an error of type T, as found by errors.As.
+func ClassifyErrorsAs[T error](class ErrorClass) ErrorClassifierFunc {
+	return func(err *gqlerror.Error) ErrorClass {
+		var target T
+		if errors.As(err, &target) {
+			return class
+		}
+		return ErrorClassUnclassified
+	}
+}
+
+// ChainErrorClassifiers returns the class given by the first classifier classifying the error.
+func ChainErrorClassifiers(classifiers ...ErrorClassifierFunc) ErrorClassifierFunc {
+	return func(err *gqlerror.Error) ErrorClass {
+		for _, classifier := range classifiers {
+			if class := classifier(err); class != ErrorClassUnclassified {
+				return class
+			}
+		}
+		return ErrorClassUnclassified
+	}
+}
+
+// recordSpanErrors records the errors on the operation or field span according to their class,
+// and sets the span status to Error if any of them is a failure.
+// The outcome is the one of the response for the operation span, whose failure status is decided by the status policy,
+// and empty for the field spans.
+// With WithFieldErrorAttribution, the operation span only records the errors not recorded on a field span,
+// and summarizes the failures in its status.
+func (a Tracer) recordSpanErrors(ctx context.Context, span oteltrace.Span, errList gqlerror.List, outcome string) {
+	operation := outcome != ""
+	failures, recorded := a.classifyErrors(errList)
+
+	failureCode := codes.Error
+	if operation {
+		failureCode = a.failureCode(outcome)
+	}
+	switch {
+	case len(failures) == 0 || failureCode == codes.Ok:
+		span.SetStatus(codes.Ok, "Finished successfully")
+	case failureCode == codes.Unset:
+	case operation && a.fieldErrorAttribution:
+		span.SetStatus(codes.Error, fmt.Sprintf("%d graphql errors", len(failures)))
+	default:
+		span.SetStatus(codes.Error, failures.Error())
+	}
+	if len(recorded) == 0 {
+		return
+	}
+
+	// the count covers all the errors, even those recorded elsewhere or above the cap
+	count := len(recorded)
+	recorded = a.recordErrorEvents(ctx, span, recorded, operation)
+	span.SetAttributes(ResolverErrors(recorded)...)
+	span.SetAttributes(resolverHasErrorKey.Bool(true), resolverErrorCountKey.Int64(int64(count)))
+}
+
+// classifyErrors returns the errors that are failures, and all the errors to record, according to the classifier.
+func (a Tracer) classifyErrors(errList gqlerror.List) (failures, recorded gqlerror.List) {
+	for _, err := range errList {
+		class := ErrorClassUnclassified
+		if a.errorClassifier != nil {
+			class = a.errorClassifier(err)
+		}
+		switch class {
+		case ErrorClassIgnored:
+			continue
+		case ErrorClassRecorded:
+		default:
+			failures = append(failures, err)
+		}
+		recorded = append(recorded, err)
+	}
+	return failures, recorded
+}
+
+// failureCode returns the status code of an operation span whose response, of the given outcome, has failures.
+func (a Tracer) failureCode(outcome string) codes.Code {
+	if a.statusPolicy == nil {
+		return codes.Error
+	}
+	return a.statusPolicy(outcome)
+}
+
+// recordErrorEvents records the errors as exception events on the span, and returns the recorded errors.
+// With WithFieldErrorAttribution, the operation span leaves out the errors recorded on a field span.
+// At most the maximum number of recorded errors are recorded.
+func (a Tracer) recordErrorEvents(ctx context.Context, span oteltrace.Span, errList gqlerror.List, operation bool) gqlerror.List {
+	if a.fieldErrorAttribution {
+		if operation {
+			errList = unattributedErrors(ctx, errList)
+		} else {
+			addAttributedErrors(ctx, errList)
+		}
+	}
+	if a.maxRecordedErrors > 0 && len(errList) > a.maxRecordedErrors {
+		errList = errList[:a.maxRecordedErrors]
+	}
+	recordErrors(span, errList)
+	return errList
+}
+
+// recordErrors records each error as an exception span event.
+func recordErrors(span oteltrace.Span, errList gqlerror.List) {
+	for _, err := range errList {
+		span.AddEvent(semconv.ExceptionEventName, oteltrace.WithAttributes(ErrorAttributes(err)...))
+	}
+}
+
+// ErrorAttributes returns the attributes describing a GraphQL error, following the exception semantic conventions:
+// the type of the underlying Go error, the message, and the path, locations, code and rule of the error.
+func ErrorAttributes(err *gqlerror.Error) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.ExceptionType(errorType(err)),
+		semconv.ExceptionMessage(err.Message),
+	}
+	if len(err.Path) > 0 {
+		attrs = append(attrs, errorPathKey.String(err.Path.String()))
+	}
+	if len(err.Locations) > 0 {
+		locations := make([]string, 0, len(err.Locations))
+		for _, location := range err.Locations {
+			locations = append(locations, fmt.Sprintf("%d:%d", location.Line, location.Column))
+		}
+		attrs = append(attrs, errorLocationsKey.StringSlice(locations))
+	}
+	if code := errorCode(err); code != "" {
+		attrs = append(attrs, errorCodeKey.String(code))
+	}
+	if err.Rule != "" {
+		attrs = append(attrs, errorRuleKey.String(err.Rule))
+	}
+	return attrs
+}
+
+// errorType returns the type of the innermost error wrapped by the GraphQL error,
+// or of the GraphQL error itself if it does not wrap any.
+func errorType(err *gqlerror.Error) string {
+	var underlying error = err
+	for next := errors.Unwrap(underlying); next != nil; next = errors.Unwrap(underlying) {
+		underlying = next
+	}
+	return fmt.Sprintf("%T", underlying)
+}
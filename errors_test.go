@@ -0,0 +1,282 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestErrorAttributes(t *testing.T) {
+	err := gqlerror.WrapPath(ast.Path{ast.PathName("user"), ast.PathIndex(0)}, fmt.Errorf("wrapped: %w", fs.ErrNotExist))
+	err.Locations = []gqlerror.Location{{Line: 1, Column: 3}}
+	err.Rule = "SomeRule"
+	errcode.Set(err, "NOT_FOUND")
+
+	assert.Equal(t, []attribute.KeyValue{
+		semconv.ExceptionType("*errors.errorString"),
+		semconv.ExceptionMessage("wrapped: file does not exist"),
+		errorPathKey.String("user[0]"),
+		errorLocationsKey.StringSlice([]string{"1:3"}),
+		errorCodeKey.String("NOT_FOUND"),
+		errorRuleKey.String("SomeRule"),
+	}, ErrorAttributes(err))
+}
+
+func TestErrorAttributesWithoutUnderlyingError(t *testing.T) {
+	assert.Equal(t, []attribute.KeyValue{
+		semconv.ExceptionType("*gqlerror.Error"),
+		semconv.ExceptionMessage("boom"),
+	}, ErrorAttributes(gqlerror.Errorf("boom")))
+}
+
+func TestErrorEvents(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServerError(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider)))
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+	spans := endedSpans(spanRecorder)
+	require.Len(t, spans, 2)
+	events := spans[1].Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, semconv.ExceptionEventName, events[0].Name)
+	assert.Contains(t, events[0].Attributes, semconv.ExceptionType("*errors.errorString"))
+	assert.Contains(t, events[0].Attributes, semconv.ExceptionMessage("resolver error"))
+	assert.Contains(t, spans[1].Attributes(), attribute.String("gql.resolver.error.0.kind", "*errors.errorString"))
+}
+
+func TestErrorClassifiers(t *testing.T) {
+	notFound := gqlerror.Errorf("not found")
+	errcode.Set(notFound, "NOT_FOUND")
+	missingFile := gqlerror.WrapPath(nil, &fs.PathError{Op: "open", Path: "f", Err: fs.ErrNotExist})
+	other := gqlerror.Errorf("other")
+
+	classifier := ChainErrorClassifiers(
+		ClassifyErrorCodes(ErrorClassRecorded, "NOT_FOUND", "UNAUTHENTICATED"),
+		ClassifyErrorsAs[*fs.PathError](ErrorClassIgnored),
+	)
+
+	assert.Equal(t, ErrorClassRecorded, classifier(notFound))
+	assert.Equal(t, ErrorClassIgnored, classifier(missingFile))
+	assert.Equal(t, ErrorClassUnclassified, classifier(other))
+}
+
+func TestWithErrorClassifier(t *testing.T) {
+	tests := []struct {
+		name       string
+		class      ErrorClass
+		wantCode   codes.Code
+		wantEvents int
+	}{
+		{name: "failure", class: ErrorClassFailure, wantCode: codes.Error, wantEvents: 1},
+		{name: "unclassified", class: ErrorClassUnclassified, wantCode: codes.Error, wantEvents: 1},
+		{name: "recorded", class: ErrorClassRecorded, wantCode: codes.Ok, wantEvents: 1},
+		{name: "ignored", class: ErrorClassIgnored, wantCode: codes.Ok},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spanRecorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+			srv := newMockServerError(func(_ context.Context) (interface{}, error) {
+				return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+			})
+			srv.Use(Middleware(
+				WithTracerProvider(provider),
+				WithErrorClassifier(func(_ *gqlerror.Error) ErrorClass { return tt.class }),
+			))
+
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+			spans := endedSpans(spanRecorder)
+			require.Len(t, spans, 2)
+			assert.Equal(t, tt.wantCode, spans[1].Status().Code)
+			assert.Len(t, spans[1].Events(), tt.wantEvents)
+			_, ok := spanAttribute(spans[1], resolverErrorCountKey)
+			assert.Equal(t, tt.wantEvents > 0, ok)
+		})
+	}
+}
+
+func TestWithFieldErrorAttribution(t *testing.T) {
+	tests := []struct {
+		name                string
+		fieldSpans          bool
+		wantFieldEvents     int
+		wantOperationEvents int
+	}{
+		{name: "field span", fieldSpans: true, wantFieldEvents: 2},
+		{name: "without field span", wantOperationEvents: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spanRecorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+			srv := newMockServer(func(ctx context.Context) (interface{}, error) {
+				graphql.AddError(ctx, fmt.Errorf("first error"))
+				graphql.AddError(ctx, fmt.Errorf("second error"))
+				return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+			})
+			srv.Use(Middleware(
+				WithTracerProvider(provider),
+				WithFieldErrorAttribution(),
+				WithCreateSpanFromFields(func(_ *graphql.FieldContext) bool { return tt.fieldSpans }),
+			))
+
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+			spans := endedSpans(spanRecorder)
+			operationSpan := spans[len(spans)-1]
+			assert.Equal(t, codes.Error, operationSpan.Status().Code)
+			assert.Equal(t, "2 graphql errors", operationSpan.Status().Description)
+			assert.Len(t, operationSpan.Events(), tt.wantOperationEvents)
+			errorCount, _ := spanAttribute(operationSpan, resolverErrorCountKey)
+			assert.Equal(t, int64(2), errorCount.AsInt64())
+
+			if tt.fieldSpans {
+				require.Len(t, spans, 2)
+				assert.Len(t, spans[0].Events(), tt.wantFieldEvents)
+				assert.Equal(t, codes.Error, spans[0].Status().Code)
+			}
+		})
+	}
+}
+
+func TestReturnedFieldError(t *testing.T) {
+	tests := []struct {
+		name                string
+		opts                []Option
+		wantFieldCode       codes.Code
+		wantFieldEvents     int
+		wantOperationEvents int
+	}{
+		{name: "failure", wantFieldCode: codes.Error, wantFieldEvents: 1, wantOperationEvents: 1},
+		{
+			name:          "ignored",
+			opts:          []Option{WithErrorClassifier(ClassifyErrorsAs[*notFoundError](ErrorClassIgnored))},
+			wantFieldCode: codes.Ok,
+		},
+		{
+			name:            "field error attribution",
+			opts:            []Option{WithFieldErrorAttribution()},
+			wantFieldCode:   codes.Error,
+			wantFieldEvents: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spanRecorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+			srv := newMockServer(func(_ context.Context) (interface{}, error) {
+				return nil, &notFoundError{}
+			})
+			srv.Use(Middleware(append([]Option{WithTracerProvider(provider)}, tt.opts...)...))
+
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+			spans := endedSpans(spanRecorder)
+			require.Len(t, spans, 2)
+			fieldSpan, operationSpan := spans[0], spans[1]
+			assert.Equal(t, tt.wantFieldCode, fieldSpan.Status().Code)
+			require.Len(t, fieldSpan.Events(), tt.wantFieldEvents)
+			if tt.wantFieldEvents > 0 {
+				assert.Contains(t, fieldSpan.Events()[0].Attributes, errorPathKey.String("alias"))
+			}
+			assert.Len(t, operationSpan.Events(), tt.wantOperationEvents)
+		})
+	}
+}
+
+func TestFieldErrorAttributionWithErrorPresenter(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return nil, fmt.Errorf("db down")
+	})
+	// the presenter masks the message of the response errors
+	srv.SetErrorPresenter(func(ctx context.Context, err error) *gqlerror.Error {
+		presented := graphql.DefaultErrorPresenter(ctx, err)
+		presented.Message = "internal error"
+		return presented
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithFieldErrorAttribution()))
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+	spans := endedSpans(spanRecorder)
+	require.Len(t, spans, 2)
+	fieldSpan, operationSpan := spans[0], spans[1]
+	require.Len(t, fieldSpan.Events(), 1)
+	assert.Contains(t, fieldSpan.Events()[0].Attributes, semconv.ExceptionMessage("db down"))
+	// the presented error is not recorded again on the operation span
+	assert.Empty(t, operationSpan.Events())
+	assert.Equal(t, codes.Error, operationSpan.Status().Code)
+}
+
+type notFoundError struct{}
+
+func (e *notFoundError) Error() string {
+	return "not found"
+}
+
+func TestWithMaxRecordedErrors(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(ctx context.Context) (interface{}, error) {
+		for i := 0; i < 5; i++ {
+			graphql.AddError(ctx, fmt.Errorf("error %d", i))
+		}
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithMaxRecordedErrors(2)))
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+	for _, span := range endedSpans(spanRecorder) {
+		assert.Len(t, span.Events(), 2)
+		errorCount, _ := spanAttribute(span, resolverErrorCountKey)
+		assert.Equal(t, int64(5), errorCount.AsInt64())
+		_, ok := spanAttribute(span, "gql.resolver.error.2.message")
+		assert.False(t, ok)
+	}
+}
@@ -16,14 +16,25 @@ package otelgqlgen
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
 
 	otelcontrib "go.opentelemetry.io/contrib"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
@@ -31,19 +42,55 @@ const (
 	tracerName      = "github.com/ravilushqa/otelgqlgen"
 	extensionName   = "OpenTelemetry"
 	complexityLimit = "ComplexityLimit"
+
+	readSpanName       = "graphql.read"
+	parsingSpanName    = "graphql.parse"
+	validationSpanName = "graphql.validate"
+
+	subscriptionEventName      = "graphql.subscription.event"
+	complexityWarningEventName = "graphql.complexity.warning"
+	truncationMarker           = "[TRUNCATED]"
 )
 
 // Tracer is a GraphQL extension that traces GraphQL requests.
 type Tracer struct {
 	complexityExtensionName     string
+	statsExtensions             []statsExtension
+	complexityWarningThreshold  float64
+	operationSpanNameFormatter  OperationSpanNameFormatterFunc
+	fieldSpanNameFormatter      FieldSpanNameFormatterFunc
+	errorClassifier             ErrorClassifierFunc
+	fieldErrorAttribution       bool
+	maxRecordedErrors           int
+	statusPolicy                StatusPolicyFunc
 	tracer                      oteltrace.Tracer
 	requestVariablesBuilderFunc RequestVariablesBuilderFunc
+	redactor                    *redactor
+	sensitiveDirective          string
+	resolvedArgs                bool
+	schema                      *atomic.Pointer[ast.Schema]
 	shouldCreateSpanFromFields  FieldsPredicateFunc
 	spanKindSelector            SpanKindSelectorFunc
+	operationSpanKindSelector   OperationSpanKindSelectorFunc
+	shouldMeasureFields         FieldsPredicateFunc
+	phaseSpans                  bool
+	subscriptionSpans           bool
+	convention                  AttributeConvention
+	querySignature              bool
+	queryHashOnly               bool
+	maxDocumentLength           int
+	shouldOmitDocument          OperationPredicateFunc
+	propagators                 propagation.TextMapPropagator
+	extensionsPropagationKey    string
+	responseTraceIDKey          string
+	responseTraceParentKey      string
+	responseTracePredicate      ResponsePredicateFunc
+	instruments                 instruments
 }
 
 var _ interface {
 	graphql.HandlerExtension
+	graphql.OperationInterceptor
 	graphql.ResponseInterceptor
 	graphql.FieldInterceptor
 } = Tracer{}
@@ -54,75 +101,431 @@ func (a Tracer) ExtensionName() string {
 }
 
 // Validate checks if the extension is configured properly.
-func (a Tracer) Validate(_ graphql.ExecutableSchema) error {
+func (a Tracer) Validate(schema graphql.ExecutableSchema) error {
+	// the schema is used to look up the sensitive input fields of the variables
+	if a.schema != nil {
+		a.schema.Store(schema.Schema())
+	}
 	return nil
 }
 
+// InterceptOperation intercepts the incoming operation.
+// With WithSubscriptionSpans, it traces the whole lifetime of subscriptions.
+func (a Tracer) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	oc := graphql.GetOperationContext(ctx)
+	if operationType(oc) != string(ast.Subscription) {
+		return next(ctx)
+	}
+	// subscriptions respond once per event, so their request metrics are recorded once here
+	opName := operationName(ctx)
+	start := time.Now()
+	a.recordRequestMetrics(ctx, oc, opName, nil)
+
+	var handler graphql.ResponseHandler
+	if a.subscriptionSpans {
+		handler = a.traceSubscription(ctx, oc, next)
+	} else {
+		handler = next(ctx)
+	}
+	return a.measureSubscription(oc, opName, start, handler)
+}
+
+// measureSubscription records the duration and count of the subscription once its stream closes,
+// with the same attributes as the other operations, describing the worst of its events.
+func (a Tracer) measureSubscription(oc *graphql.OperationContext, opName string, start time.Time, handler graphql.ResponseHandler) graphql.ResponseHandler {
+	if !oc.Stats.OperationStart.IsZero() {
+		start = oc.Stats.OperationStart
+	}
+
+	hasError := false
+	outcome := ResponseOutcomeSuccess
+	return func(ctx context.Context) *graphql.Response {
+		resp := handler(ctx)
+		if resp != nil {
+			hasError = hasError || len(resp.Errors) > 0
+			switch eventOutcome := responseOutcome(resp); {
+			case eventOutcome == ResponseOutcomeFailure:
+				outcome = ResponseOutcomeFailure
+			case eventOutcome == ResponseOutcomePartial && outcome == ResponseOutcomeSuccess:
+				outcome = ResponseOutcomePartial
+			}
+			return resp
+		}
+
+		attrs := metric.WithAttributes(
+			RequestOperationName(opName),
+			RequestOperationType(operationType(oc)),
+			ResponseHasError(hasError),
+			ResponseOutcome(outcome),
+		)
+		a.instruments.operationDuration.Record(ctx, time.Since(start).Seconds(), attrs)
+		a.instruments.requestCount.Add(ctx, 1, attrs)
+		return nil
+	}
+}
+
+// traceSubscription runs the subscription within a span that ends when the stream closes.
+// Each event is recorded as a span event.
+func (a Tracer) traceSubscription(ctx context.Context, oc *graphql.OperationContext, next graphql.OperationHandler) graphql.ResponseHandler {
+	ctx, span := a.startOperationSpan(ctx, oc, operationName(ctx))
+	span.SetAttributes(persistedQueryAttributes(oc, nil)...)
+	handler := next(ctx)
+
+	var sequence, failedEvents, unsetEvents int64
+	return func(ctx context.Context) *graphql.Response {
+		// the events are resolved with the context of the transport, which does not carry the span.
+		// Each event gets its own state, to leave out of the span the errors recorded on its field spans.
+		ctx = withOperationState(oteltrace.ContextWithSpan(ctx, span))
+		resp := handler(ctx)
+		if resp == nil {
+			span.SetAttributes(SubscriptionEventCount(sequence))
+			switch {
+			case failedEvents > 0:
+				span.SetStatus(codes.Error, fmt.Sprintf("%d subscription events with errors", failedEvents))
+			case unsetEvents > 0:
+			default:
+				span.SetStatus(codes.Ok, "Finished successfully")
+			}
+			span.End()
+			return nil
+		}
+
+		sequence++
+		outcome := responseOutcome(resp)
+		attrs := []attribute.KeyValue{
+			SubscriptionSequence(sequence),
+			SubscriptionPayloadSize(int64(len(resp.Data))),
+		}
+		failures, recorded := a.classifyErrors(resp.Errors)
+		if len(failures) > 0 {
+			// like for the operation spans, the status policy decides whether the event fails the subscription
+			switch a.failureCode(outcome) {
+			case codes.Error:
+				failedEvents++
+			case codes.Unset:
+				unsetEvents++
+			}
+		}
+		if len(recorded) > 0 {
+			attrs = append(attrs,
+				ResponseOutcome(outcome),
+				resolverHasErrorKey.Bool(true),
+				resolverErrorCountKey.Int64(int64(len(recorded))),
+			)
+		}
+		span.AddEvent(subscriptionEventName, oteltrace.WithAttributes(attrs...))
+		a.recordErrorEvents(ctx, span, recorded, true)
+		a.setResponseTraceExtensions(ctx, span.SpanContext(), resp)
+
+		return resp
+	}
+}
+
 // InterceptResponse intercepts the incoming request.
 func (a Tracer) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
 	if !graphql.HasOperationContext(ctx) {
-		return next(ctx)
+		// the request was rejected before its operation context was created, e.g. for a malformed body,
+		// so it is traced with an empty one, which is not passed on to the next interceptors
+		next = withCallerContext(ctx, next)
+		ctx = graphql.WithOperationContext(ctx, &graphql.OperationContext{})
+	}
+	if getOperationState(ctx) == nil {
+		// the events of the subscription spans already hold the state created by traceSubscription
+		ctx = withOperationState(ctx)
 	}
 
+	oc := graphql.GetOperationContext(ctx)
 	opName := operationName(ctx)
-	spanKind := a.spanKindSelector(opName)
-	ctx, span := a.tracer.Start(ctx, opName, oteltrace.WithSpanKind(spanKind))
+	start := time.Now()
+	if operationType(oc) != string(ast.Subscription) {
+		resp := a.traceResponse(ctx, opName, next)
+		a.recordOperationMetrics(ctx, opName, resp, start)
+		return resp
+	}
+
+	// the metrics of subscriptions are recorded by InterceptOperation,
+	// which is not called for the subscriptions rejected before their execution, e.g. by the complexity limit
+	if a.subscriptionSpans {
+		// the subscription span is started by InterceptOperation
+		resp := next(ctx)
+		if rejectionPhase(oc, resp) == "" {
+			return resp
+		}
+		resp = a.traceResponse(ctx, opName, graphql.OneShot(resp))
+		a.recordOperationMetrics(ctx, opName, resp, start)
+		return resp
+	}
+	resp := a.traceResponse(ctx, opName, next)
+	if rejectionPhase(oc, resp) != "" {
+		a.recordOperationMetrics(ctx, opName, resp, start)
+	}
+	return resp
+}
+
+// withCallerContext returns a response handler calling next with the caller context,
+// along with the span of the context it is called with.
+func withCallerContext(callerCtx context.Context, next graphql.ResponseHandler) graphql.ResponseHandler {
+	return func(ctx context.Context) *graphql.Response {
+		return next(oteltrace.ContextWithSpan(callerCtx, oteltrace.SpanFromContext(ctx)))
+	}
+}
+
+// traceResponse runs the response handler within the operation span.
+func (a Tracer) traceResponse(ctx context.Context, opName string, next graphql.ResponseHandler) *graphql.Response {
+	ctx, span := a.startOperationSpan(ctx, graphql.GetOperationContext(ctx), opName)
 	defer span.End()
 	if !span.IsRecording() {
-		return next(ctx)
+		resp := next(ctx)
+		a.setResponseTraceExtensions(ctx, span.SpanContext(), resp)
+		return resp
 	}
 
-	oc := graphql.GetOperationContext(ctx)
+	resp := next(ctx)
+	// a persisted query miss is only known from the response errors
+	span.SetAttributes(persistedQueryAttributes(graphql.GetOperationContext(ctx), resp)...)
+	if resp != nil {
+		// like the error attributes, the outcome is only recorded on the spans with errors
+		outcome := responseOutcome(resp)
+		if outcome != ResponseOutcomeSuccess {
+			span.SetAttributes(ResponseOutcome(outcome))
+		}
+		a.recordSpanErrors(ctx, span, resp.Errors, outcome)
+		if phase := rejectionPhase(graphql.GetOperationContext(ctx), resp); phase != "" {
+			span.SetAttributes(RequestRejectionPhase(phase))
+		}
+	} else {
+		span.SetStatus(codes.Ok, "Finished successfully")
+	}
+	a.setResponseTraceExtensions(ctx, span.SpanContext(), resp)
 
-	span.SetAttributes(
-		RequestQuery(oc.RawQuery),
-	)
-	complexityExtension := a.complexityExtensionName
-	if complexityExtension == "" {
-		complexityExtension = complexityLimit
+	return resp
+}
+
+// startOperationSpan starts the operation span and records the operation attributes on it.
+func (a Tracer) startOperationSpan(ctx context.Context, oc *graphql.OperationContext, opName string) (context.Context, oteltrace.Span) {
+	// without subscription spans, subscriptions get an operation span per event,
+	// so the phases are not recorded for them.
+	recordPhases := a.phaseSpans && (a.subscriptionSpans || operationType(oc) != string(ast.Subscription))
+
+	spanKind := a.spanKindSelector(opName)
+	if a.operationSpanKindSelector != nil {
+		spanKind = a.operationSpanKindSelector(oc)
 	}
-	complexityStats, ok := oc.Stats.GetExtension(complexityExtension).(*extension.ComplexityStats)
-	if !ok {
-		// complexity extension is not used
-		complexityStats = &extension.ComplexityStats{}
+	spanOpts := []oteltrace.SpanStartOption{oteltrace.WithSpanKind(spanKind)}
+	if recordPhases && !oc.Stats.OperationStart.IsZero() {
+		// the phases happen before the interceptors are called,
+		// so the operation span is back-dated to contain them.
+		spanOpts = append(spanOpts, oteltrace.WithTimestamp(oc.Stats.OperationStart))
+	}
+	spanName := opName
+	switch {
+	case a.operationSpanNameFormatter != nil:
+		spanName = a.operationSpanNameFormatter(ctx, oc)
+	case a.convention == SemConvConvention:
+		spanName = semconvSpanName(ctx, oc)
+	}
+	ctx = a.extractExtensionsContext(ctx, oc)
+	ctx, span := a.tracer.Start(ctx, spanName, spanOpts...)
+	if !span.IsRecording() {
+		return ctx, span
+	}
+
+	if recordPhases {
+		a.recordPhaseSpans(ctx, oc)
 	}
 
-	if complexityStats.ComplexityLimit > 0 {
+	span.SetAttributes(a.operationAttributes(ctx, oc)...)
+	if complexityStats := a.complexityStats(oc); complexityStats != nil && complexityStats.ComplexityLimit > 0 {
 		span.SetAttributes(
 			RequestComplexityLimit(int64(complexityStats.ComplexityLimit)),
 			RequestOperationComplexity(int64(complexityStats.Complexity)),
 		)
+		if a.complexityWarningThreshold > 0 &&
+			float64(complexityStats.Complexity) >= a.complexityWarningThreshold/100*float64(complexityStats.ComplexityLimit) {
+			span.AddEvent(complexityWarningEventName, oteltrace.WithAttributes(
+				RequestComplexityLimit(int64(complexityStats.ComplexityLimit)),
+				RequestOperationComplexity(int64(complexityStats.Complexity)),
+			))
+		}
+	}
+	for _, ext := range a.statsExtensions {
+		if stats := oc.Stats.GetExtension(ext.name); stats != nil {
+			span.SetAttributes(ext.mapper(stats)...)
+		}
 	}
 
 	if a.requestVariablesBuilderFunc != nil {
-		span.SetAttributes(a.requestVariablesBuilderFunc(oc.Variables)...)
+		variables, redacted := a.redactor.withPaths(a.sensitiveVariablePaths(oc)).redactVariables(oc.Variables)
+		span.SetAttributes(a.requestVariablesBuilderFunc(variables)...)
+		if len(redacted) > 0 {
+			span.SetAttributes(RequestVariablesRedacted(redacted))
+		}
 	}
 
-	resp := next(ctx)
-	if resp != nil && len(resp.Errors) > 0 {
-		span.SetStatus(codes.Error, resp.Errors.Error())
-		span.RecordError(fmt.Errorf("graphql response errors: %v", resp.Errors.Error()))
-		span.SetAttributes(ResolverErrors(resp.Errors)...)
-	} else {
-		span.SetStatus(codes.Ok, "Finished successfully")
+	return ctx, span
+}
+
+// complexityStats returns the stats of the complexity extension, or nil if it is not used.
+func (a Tracer) complexityStats(oc *graphql.OperationContext) *extension.ComplexityStats {
+	complexityExtension := a.complexityExtensionName
+	if complexityExtension == "" {
+		complexityExtension = complexityLimit
 	}
+	complexityStats, _ := oc.Stats.GetExtension(complexityExtension).(*extension.ComplexityStats)
+	return complexityStats
+}
 
-	return resp
+// operationAttributes returns the attributes describing the operation
+// according to the configured convention.
+func (a Tracer) operationAttributes(ctx context.Context, oc *graphql.OperationContext) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	document, recordDocument := a.document(oc)
+	if a.convention != SemConvConvention && recordDocument {
+		attrs = append(attrs, RequestQuery(document))
+	}
+	if a.convention != LegacyConvention && recordDocument {
+		attrs = append(attrs, RequestDocument(document))
+	}
+	if document != oc.RawQuery {
+		attrs = append(attrs, RequestQueryLength(len(oc.RawQuery)))
+	}
+	if a.querySignature && oc.Operation != nil {
+		signature := querySignature(oc.Doc, oc.Operation)
+		if !a.queryHashOnly {
+			attrs = append(attrs, RequestSignature(signature))
+		}
+		attrs = append(attrs, RequestSignatureHash(querySignatureHash(signature)))
+	}
+	if a.convention != LegacyConvention {
+		if opName := providedOperationName(ctx); opName != "" {
+			attrs = append(attrs, RequestOperationName(opName))
+		}
+	}
+	if opType := operationType(oc); opType != "" {
+		attrs = append(attrs, RequestOperationType(opType))
+	}
+	return attrs
+}
+
+// document returns the query to record, truncated to the maximum length,
+// and whether it is recorded at all.
+func (a Tracer) document(oc *graphql.OperationContext) (string, bool) {
+	if a.queryHashOnly || (a.shouldOmitDocument != nil && a.shouldOmitDocument(oc)) {
+		return "", false
+	}
+	return truncateDocument(oc.RawQuery, a.maxDocumentLength), true
+}
+
+// truncateDocument truncates the document to at most maxLength bytes, on a rune boundary,
+// and appends the truncation marker. A zero or negative maxLength disables the truncation.
+func truncateDocument(document string, maxLength int) string {
+	if maxLength <= 0 || len(document) <= maxLength {
+		return document
+	}
+	end := maxLength
+	for end > 0 && !utf8.RuneStart(document[end]) {
+		end--
+	}
+	return document[:end] + truncationMarker
+}
+
+// recordPhaseSpans records the read, parse and validation phases of the operation
+// as back-dated child spans, using the timings collected by gqlgen.
+func (a Tracer) recordPhaseSpans(ctx context.Context, oc *graphql.OperationContext) {
+	phases := []struct {
+		name   string
+		timing graphql.TraceTiming
+	}{
+		{readSpanName, oc.Stats.Read},
+		{parsingSpanName, oc.Stats.Parsing},
+		{validationSpanName, oc.Stats.Validation},
+	}
+	for _, phase := range phases {
+		if phase.timing.Start.IsZero() || phase.timing.End.IsZero() {
+			continue
+		}
+		_, span := a.tracer.Start(ctx, phase.name,
+			oteltrace.WithSpanKind(oteltrace.SpanKindInternal),
+			oteltrace.WithTimestamp(phase.timing.Start),
+		)
+		span.End(oteltrace.WithTimestamp(phase.timing.End))
+	}
+}
+
+// recordOperationMetrics records the duration and count of the operation.
+// Metrics are recorded regardless of whether the operation span is sampled.
+func (a Tracer) recordOperationMetrics(ctx context.Context, opName string, resp *graphql.Response, start time.Time) {
+	oc := graphql.GetOperationContext(ctx)
+	if !oc.Stats.OperationStart.IsZero() {
+		start = oc.Stats.OperationStart
+	}
+
+	attrs := metric.WithAttributes(
+		RequestOperationName(opName),
+		RequestOperationType(operationType(oc)),
+		ResponseHasError(resp != nil && len(resp.Errors) > 0),
+		ResponseOutcome(responseOutcome(resp)),
+	)
+	a.instruments.operationDuration.Record(ctx, time.Since(start).Seconds(), attrs)
+	a.instruments.requestCount.Add(ctx, 1, attrs)
+	a.recordRequestMetrics(ctx, oc, opName, resp)
+}
+
+// recordRequestMetrics records the metrics measured once per request rather than once per response:
+// the persisted query outcome and the operation complexity.
+func (a Tracer) recordRequestMetrics(ctx context.Context, oc *graphql.OperationContext, opName string, resp *graphql.Response) {
+	a.recordPersistedQuery(ctx, oc, resp)
+	if complexityStats := a.complexityStats(oc); complexityStats != nil {
+		a.instruments.operationComplexity.Record(ctx, int64(complexityStats.Complexity), metric.WithAttributes(
+			RequestOperationName(opName),
+			RequestOperationType(operationType(oc)),
+		))
+	}
 }
 
 // InterceptField intercepts the incoming request.
 func (a Tracer) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
 	fc := graphql.GetFieldContext(ctx)
+	if a.shouldMeasureFields == nil || !a.shouldMeasureFields(fc) {
+		return a.traceField(ctx, fc, next)
+	}
+
+	start := time.Now()
+	resp, err := a.traceField(ctx, fc, next)
+	a.recordResolverMetrics(ctx, fc, err, start)
+
+	return resp, err
+}
+
+// traceField runs the resolver within the field span.
+func (a Tracer) traceField(ctx context.Context, fc *graphql.FieldContext, next graphql.Resolver) (interface{}, error) {
 	if !a.shouldCreateSpanFromFields(fc) {
 		return next(ctx)
 	}
 	name := fc.Field.ObjectDefinition.Name + "/" + fc.Field.Name
 	spanKind := a.spanKindSelector(name)
+	if a.fieldSpanNameFormatter != nil {
+		name = a.fieldSpanNameFormatter(ctx, fc)
+	}
 	ctx, span := a.tracer.Start(ctx,
 		name,
 		oteltrace.WithSpanKind(spanKind),
 	)
-	defer span.End()
+	defer func() {
+		if r := recover(); r != nil {
+			// the panic is recorded on the field span, which ends before gqlgen recovers it.
+			// The span is ended once the panic is recovered, so that the SDK does not record it again.
+			// Like the other metrics, the panic is counted even if the span is not sampled.
+			a.recordPanic(ctx, span, r, ResolverObject(fc.Field.ObjectDefinition.Name), ResolverField(fc.Field.Name))
+			addRecordedPanic(ctx, fc)
+			span.End()
+			panic(r)
+		}
+		span.End()
+	}()
 	if !span.IsRecording() {
 		return next(ctx)
 	}
@@ -133,22 +536,32 @@ func (a Tracer) InterceptField(ctx context.Context, next graphql.Resolver) (inte
 		ResolverField(fc.Field.Name),
 		ResolverAlias(fc.Field.Alias),
 	)
-	span.SetAttributes(ResolverArgs(fc.Field.Arguments)...)
+	span.SetAttributes(a.resolverArgs(fc)...)
 
 	resp, err := next(ctx)
 
 	errList := graphql.GetFieldErrors(ctx, fc)
-	if len(errList) != 0 {
-		span.SetStatus(codes.Error, errList.Error())
-		span.RecordError(fmt.Errorf("graphql field errors: %v", errList.Error()))
-		span.SetAttributes(ResolverErrors(errList)...)
-	} else {
-		span.SetStatus(codes.Ok, "Finished successfully")
+	var gqlErr *gqlerror.Error
+	if errors.As(graphql.ErrorOnPath(ctx, err), &gqlErr) {
+		// the generated code adds the returned error to the response after the field middlewares,
+		// so it is not among the field errors yet
+		errList = append(errList, gqlErr)
 	}
+	a.recordSpanErrors(ctx, span, errList, "")
 
 	return resp, err
 }
 
+// recordResolverMetrics records the duration of the resolver.
+// The path is deliberately left out of the attributes to keep the cardinality bounded.
+func (a Tracer) recordResolverMetrics(ctx context.Context, fc *graphql.FieldContext, err error, start time.Time) {
+	a.instruments.resolverDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+		ResolverObject(fc.Field.ObjectDefinition.Name),
+		ResolverField(fc.Field.Name),
+		ResolverHasError(err != nil || len(graphql.GetFieldErrors(ctx, fc)) > 0),
+	))
+}
+
 // Middleware sets up a handler to start tracing the incoming
 // requests.  The service parameter should describe the name of the
 // (virtual) server handling the request. extension parameter may be empty string.
@@ -160,6 +573,12 @@ func Middleware(opts ...Option) Tracer {
 	if cfg.TracerProvider == nil {
 		cfg.TracerProvider = otel.GetTracerProvider()
 	}
+	if cfg.MeterProvider == nil {
+		cfg.MeterProvider = otel.GetMeterProvider()
+	}
+	if cfg.Propagators == nil {
+		cfg.Propagators = otel.GetTextMapPropagator()
+	}
 	if cfg.RequestVariablesBuilder == nil {
 		cfg.RequestVariablesBuilder = RequestVariables
 	}
@@ -169,17 +588,60 @@ func Middleware(opts ...Option) Tracer {
 	if cfg.SpanKindSelectorFunc == nil {
 		cfg.SpanKindSelectorFunc = alwaysServer()
 	}
+	if cfg.StatusPolicy == nil {
+		cfg.StatusPolicy = DefaultStatusPolicy
+	}
+	if cfg.ResolverMetrics && cfg.ShouldMeasureFields == nil {
+		cfg.ShouldMeasureFields = cfg.ShouldCreateSpanFromFields
+	}
+
+	var variablesRedactor *redactor
+	if cfg.RedactionPolicy != nil {
+		variablesRedactor = newRedactor(*cfg.RedactionPolicy)
+	}
 
 	tracer := cfg.TracerProvider.Tracer(
 		tracerName,
 		oteltrace.WithInstrumentationVersion(otelcontrib.Version()),
 	)
+	meter := cfg.MeterProvider.Meter(
+		tracerName,
+		metric.WithInstrumentationVersion(otelcontrib.Version()),
+	)
 
 	return Tracer{
 		tracer:                      tracer,
 		requestVariablesBuilderFunc: cfg.RequestVariablesBuilder,
+		redactor:                    variablesRedactor,
+		sensitiveDirective:          cfg.SensitiveDirective,
+		resolvedArgs:                cfg.ResolvedArgs,
+		schema:                      &atomic.Pointer[ast.Schema]{},
 		shouldCreateSpanFromFields:  cfg.ShouldCreateSpanFromFields,
 		spanKindSelector:            cfg.SpanKindSelectorFunc,
+		operationSpanKindSelector:   cfg.OperationSpanKindSelector,
+		shouldMeasureFields:         cfg.ShouldMeasureFields,
+		phaseSpans:                  !cfg.DisablePhaseSpans,
+		subscriptionSpans:           cfg.SubscriptionSpans,
+		convention:                  cfg.AttributeConvention,
+		complexityExtensionName:     cfg.ComplexityExtensionName,
+		statsExtensions:             cfg.StatsExtensions,
+		complexityWarningThreshold:  cfg.ComplexityWarningThreshold,
+		operationSpanNameFormatter:  cfg.OperationSpanNameFormatter,
+		fieldSpanNameFormatter:      cfg.FieldSpanNameFormatter,
+		errorClassifier:             cfg.ErrorClassifier,
+		fieldErrorAttribution:       cfg.FieldErrorAttribution,
+		maxRecordedErrors:           cfg.MaxRecordedErrors,
+		statusPolicy:                cfg.StatusPolicy,
+		querySignature:              cfg.QuerySignature,
+		queryHashOnly:               cfg.QueryHashOnly,
+		maxDocumentLength:           cfg.MaxDocumentLength,
+		shouldOmitDocument:          cfg.ShouldOmitDocument,
+		propagators:                 cfg.Propagators,
+		extensionsPropagationKey:    cfg.ExtensionsPropagationKey,
+		responseTraceIDKey:          cfg.ResponseTraceIDKey,
+		responseTraceParentKey:      cfg.ResponseTraceParentKey,
+		responseTracePredicate:      cfg.ResponseTracePredicate,
+		instruments:                 newInstruments(meter),
 	}
 
 }
@@ -198,6 +660,15 @@ func alwaysServer() SpanKindSelectorFunc {
 }
 
 func operationName(ctx context.Context) string {
+	if opName := providedOperationName(ctx); opName != "" {
+		return opName
+	}
+	return GetOperationName(ctx)
+}
+
+// providedOperationName returns the operation name from the request or the context,
+// or an empty string for anonymous operations.
+func providedOperationName(ctx context.Context) string {
 	opContext := graphql.GetOperationContext(ctx)
 	if opName := opContext.OperationName; opName != "" {
 		return opName
@@ -205,7 +676,68 @@ func operationName(ctx context.Context) string {
 	if opContext.Operation != nil && opContext.Operation.Name != "" {
 		return opContext.Operation.Name
 	}
-	return GetOperationName(ctx)
+	opName, _ := ctx.Value(operationNameCtxKey{}).(string)
+	return opName
+}
+
+// TopLevelFieldsSpanName is an operation span name formatter naming the anonymous operations
+// after their type and sorted top-level fields, e.g. "query posts,user" for "{ user posts }".
+// Named operations keep their name.
+func TopLevelFieldsSpanName(ctx context.Context, oc *graphql.OperationContext) string {
+	if opName := providedOperationName(ctx); opName != "" || oc.Operation == nil {
+		return operationName(ctx)
+	}
+
+	fields := make(map[string]struct{})
+	collectTopLevelFields(oc.Operation.SelectionSet, fields, make(map[string]struct{}))
+	if len(fields) == 0 {
+		return operationName(ctx)
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return operationType(oc) + " " + strings.Join(names, ",")
+}
+
+// collectTopLevelFields collects the names of the fields of the selection set, including those of its fragments.
+func collectTopLevelFields(selectionSet ast.SelectionSet, fields, fragments map[string]struct{}) {
+	for _, selection := range selectionSet {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			fields[selection.Name] = struct{}{}
+		case *ast.InlineFragment:
+			collectTopLevelFields(selection.SelectionSet, fields, fragments)
+		case *ast.FragmentSpread:
+			if _, ok := fragments[selection.Name]; ok || selection.Definition == nil {
+				continue
+			}
+			fragments[selection.Name] = struct{}{}
+			collectTopLevelFields(selection.Definition.SelectionSet, fields, fragments)
+		}
+	}
+}
+
+// semconvSpanName returns the operation span name recommended by the semantic conventions.
+func semconvSpanName(ctx context.Context, oc *graphql.OperationContext) string {
+	opType := operationType(oc)
+	if opType == "" {
+		return "GraphQL Operation"
+	}
+	if opName := providedOperationName(ctx); opName != "" {
+		return opType + " " + opName
+	}
+	return opType
+}
+
+// operationType returns the type of the operation (query, mutation or subscription),
+// or an empty string if the operation is unknown.
+func operationType(oc *graphql.OperationContext) string {
+	if oc.Operation == nil {
+		return ""
+	}
+	return string(oc.Operation.Operation)
 }
 
 type operationNameCtxKey struct{}
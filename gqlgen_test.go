@@ -28,6 +28,7 @@ import (
 	"github.com/99designs/gqlgen/graphql/handler/extension"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/vektah/gqlparser/v2"
 	"github.com/vektah/gqlparser/v2/ast"
 	"github.com/vektah/gqlparser/v2/gqlerror"
@@ -214,7 +215,7 @@ func TestChildSpanWithDropFromFields(t *testing.T) {
 
 	srv.ServeHTTP(w, r)
 
-	spans := spanRecorder.Ended()
+	spans := endedSpans(spanRecorder)
 	if got, expected := len(spans), 1; got != expected {
 		t.Fatalf("got %d spans, expected %d", got, expected)
 	}
@@ -228,7 +229,7 @@ func TestChildSpanWithDropFromFields(t *testing.T) {
 		t.Errorf("expected name on span %s; got: %q", namelessQueryName, responseSpan.Name())
 	}
 
-	for _, s := range spanRecorder.Ended() {
+	for _, s := range endedSpans(spanRecorder) {
 		assert.Equal(t, s.Status().Code, codes.Ok)
 	}
 
@@ -313,7 +314,7 @@ func TestChildSpanFromGlobalTracerWithComplexity(t *testing.T) {
 	testSpans(t, spanRecorder, namelessQueryName, codes.Ok, trace.SpanKindServer)
 
 	// second span because it's response span where stored RequestComplexityLimit attribute
-	attributes := spanRecorder.Ended()[1].Attributes()
+	attributes := endedSpans(spanRecorder)[1].Attributes()
 	var found bool
 	for _, a := range attributes {
 		if a.Key == ("gql.request.complexityLimit") {
@@ -326,6 +327,106 @@ func TestChildSpanFromGlobalTracerWithComplexity(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
 }
 
+func TestWithComplexityExtensionName(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithComplexityExtensionName("CustomComplexity")))
+	srv.Use(statsSetter{name: "CustomComplexity", stats: &extension.ComplexityStats{
+		Complexity:      3,
+		ComplexityLimit: testComplexity,
+	}})
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	responseSpan := endedSpans(spanRecorder)[1]
+	limit, ok := spanAttribute(responseSpan, requestComplexityLimitKey)
+	assert.True(t, ok)
+	assert.Equal(t, int64(testComplexity), limit.AsInt64())
+	complexity, _ := spanAttribute(responseSpan, requestOperationComplexityKey)
+	assert.Equal(t, int64(3), complexity.AsInt64())
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestWithComplexityWarningThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		wantEvent bool
+	}{
+		{name: "reached", threshold: 80, wantEvent: true},
+		{name: "not reached", threshold: 90},
+		{name: "disabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spanRecorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+			srv := newMockServer(func(_ context.Context) (interface{}, error) {
+				return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+			})
+			srv.Use(Middleware(WithTracerProvider(provider), WithComplexityWarningThreshold(tt.threshold)))
+			srv.Use(statsSetter{name: complexityLimit, stats: &extension.ComplexityStats{Complexity: 8, ComplexityLimit: 10}})
+
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+			events := endedSpans(spanRecorder)[1].Events()
+			if !tt.wantEvent {
+				assert.Empty(t, events)
+				return
+			}
+			require.Len(t, events, 1)
+			assert.Equal(t, complexityWarningEventName, events[0].Name)
+			assert.Contains(t, events[0].Attributes, RequestOperationComplexity(8))
+			assert.Contains(t, events[0].Attributes, RequestComplexityLimit(10))
+		})
+	}
+}
+
+func TestWithStatsExtension(t *testing.T) {
+	type cacheStats struct {
+		Hits int64
+	}
+
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(
+		WithTracerProvider(provider),
+		WithStatsExtension("Cache", func(stats interface{}) []attribute.KeyValue {
+			return []attribute.KeyValue{attribute.Int64("cache.hits", stats.(*cacheStats).Hits)}
+		}),
+		WithStatsExtension("Missing", func(_ interface{}) []attribute.KeyValue {
+			t.Fatal("the mapper of a missing extension must not be called")
+			return nil
+		}),
+	))
+	srv.Use(statsSetter{name: "Cache", stats: &cacheStats{Hits: 2}})
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	hits, ok := spanAttribute(endedSpans(spanRecorder)[1], "cache.hits")
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), hits.AsInt64())
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
 func TestOperationName(t *testing.T) {
 	operation := "ExampleOperationName"
 	ctx := SetOperationName(context.Background(), operation)
@@ -377,10 +478,11 @@ func TestVariablesAttributes(t *testing.T) {
 
 	testSpans(t, spanRecorder, namelessQueryName, codes.Ok, trace.SpanKindServer)
 
-	spans := spanRecorder.Ended()
-	assert.Len(t, spans[1].Attributes(), 2)
+	spans := endedSpans(spanRecorder)
+	assert.Len(t, spans[1].Attributes(), 3)
 	assert.Equal(t, attribute.Key("gql.request.query"), spans[1].Attributes()[0].Key)
-	assert.Equal(t, attribute.Key("gql.request.variables.id"), spans[1].Attributes()[1].Key)
+	assert.Equal(t, attribute.Key("graphql.operation.type"), spans[1].Attributes()[1].Key)
+	assert.Equal(t, attribute.Key("gql.request.variables.id"), spans[1].Attributes()[2].Key)
 
 	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
 }
@@ -416,10 +518,11 @@ func TestVariablesAttributesCustomBuilder(t *testing.T) {
 
 	testSpans(t, spanRecorder, namelessQueryName, codes.Ok, trace.SpanKindServer)
 
-	spans := spanRecorder.Ended()
-	assert.Len(t, spans[1].Attributes(), 2)
+	spans := endedSpans(spanRecorder)
+	assert.Len(t, spans[1].Attributes(), 3)
 	assert.Equal(t, attribute.Key("gql.request.query"), spans[1].Attributes()[0].Key)
-	assert.Equal(t, attribute.Key("id"), spans[1].Attributes()[1].Key)
+	assert.Equal(t, attribute.Key("graphql.operation.type"), spans[1].Attributes()[1].Key)
+	assert.Equal(t, attribute.Key("id"), spans[1].Attributes()[2].Key)
 
 	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
 }
@@ -447,9 +550,10 @@ func TestVariablesAttributesDisabled(t *testing.T) {
 
 	testSpans(t, spanRecorder, namelessQueryName, codes.Ok, trace.SpanKindServer)
 
-	spans := spanRecorder.Ended()
-	assert.Len(t, spans[1].Attributes(), 1)
+	spans := endedSpans(spanRecorder)
+	assert.Len(t, spans[1].Attributes(), 2)
 	assert.Equal(t, attribute.Key("gql.request.query"), spans[1].Attributes()[0].Key)
+	assert.Equal(t, attribute.Key("graphql.operation.type"), spans[1].Attributes()[1].Key)
 
 	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
 }
@@ -506,6 +610,339 @@ func TestWithSpanKindSelector(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
 }
 
+func TestWithSpanNameFormatter(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(
+		WithTracerProvider(provider),
+		WithSpanNameFormatter(
+			func(_ context.Context, oc *graphql.OperationContext) string {
+				return string(oc.Operation.Operation) + " " + oc.OperationName
+			},
+			func(_ context.Context, fc *graphql.FieldContext) string {
+				return fc.Object + "." + fc.Field.Name
+			},
+		),
+	))
+
+	body := strings.NewReader(fmt.Sprintf("{\"operationName\":\"%s\",\"query\":\"query %s { name }\"}", testQueryName, testQueryName))
+	r := httptest.NewRequest("POST", "/foo", body)
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	spans := endedSpans(spanRecorder)
+	require.Len(t, spans, 2)
+	assert.Equal(t, "Query.name", spans[0].Name())
+	assert.Equal(t, "query "+testQueryName, spans[1].Name())
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestTopLevelFieldsSpanName(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{name: "anonymous", query: "{ name find(id: 1) }", expected: "query find,name"},
+		{name: "fragments", query: "{ ... on Query { name } ...F } fragment F on Query { find(id: 1) name }", expected: "query find,name"},
+		{name: "named", query: "query " + testQueryName + " { name }", expected: testQueryName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spanRecorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+			srv := newMockServer(func(_ context.Context) (interface{}, error) {
+				return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+			})
+			srv.Use(Middleware(WithTracerProvider(provider), WithSpanNameFormatter(TopLevelFieldsSpanName, nil)))
+
+			r := httptest.NewRequest("GET", "/foo?query="+url.QueryEscape(tt.query), nil)
+			w := httptest.NewRecorder()
+
+			srv.ServeHTTP(w, r)
+
+			spans := endedSpans(spanRecorder)
+			require.Len(t, spans, 2)
+			assert.Equal(t, "name/name", spans[0].Name())
+			assert.Equal(t, tt.expected, spans[1].Name())
+
+			assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestPhaseSpans(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider)))
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	spans := spanRecorder.Ended()
+	if got, expected := len(spans), 5; got != expected {
+		t.Fatalf("got %d spans, expected %d", got, expected)
+	}
+	responseSpan := spans[4]
+	assert.Equal(t, namelessQueryName, responseSpan.Name())
+	for i, name := range []string{readSpanName, parsingSpanName, validationSpanName} {
+		phaseSpan := spans[i]
+		assert.Equal(t, name, phaseSpan.Name())
+		assert.Equal(t, trace.SpanKindInternal, phaseSpan.SpanKind())
+		assert.Equal(t, responseSpan.SpanContext().SpanID(), phaseSpan.Parent().SpanID())
+		assert.False(t, phaseSpan.StartTime().Before(responseSpan.StartTime()))
+		assert.False(t, phaseSpan.EndTime().After(responseSpan.EndTime()))
+	}
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestWithoutPhaseSpans(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithoutPhaseSpans()))
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	assert.Len(t, spanRecorder.Ended(), 2)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestSemConvConvention(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithAttributeConvention(SemConvConvention)))
+
+	body := strings.NewReader(fmt.Sprintf("{\"operationName\":\"%s\",\"variables\":{},\"query\":\"query %s {\\n  name\\n}\\n\"}", testQueryName, testQueryName))
+	r := httptest.NewRequest("POST", "/foo", body)
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	testSpans(t, spanRecorder, "query "+testQueryName, codes.Ok, trace.SpanKindServer)
+
+	responseSpan := endedSpans(spanRecorder)[1]
+	document, ok := spanAttribute(responseSpan, "graphql.document")
+	assert.True(t, ok)
+	assert.Equal(t, "query NamedQuery {\n  name\n}\n", document.AsString())
+	opName, _ := spanAttribute(responseSpan, "graphql.operation.name")
+	assert.Equal(t, testQueryName, opName.AsString())
+	opType, _ := spanAttribute(responseSpan, "graphql.operation.type")
+	assert.Equal(t, "query", opType.AsString())
+	_, ok = spanAttribute(responseSpan, "gql.request.query")
+	assert.False(t, ok)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestSemConvConventionAnonymousOperation(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithAttributeConvention(SemConvConvention)))
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	testSpans(t, spanRecorder, "query", codes.Ok, trace.SpanKindServer)
+
+	_, ok := spanAttribute(endedSpans(spanRecorder)[1], "graphql.operation.name")
+	assert.False(t, ok)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestDuplicateConvention(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithAttributeConvention(DuplicateConvention)))
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	testSpans(t, spanRecorder, namelessQueryName, codes.Ok, trace.SpanKindServer)
+
+	responseSpan := endedSpans(spanRecorder)[1]
+	query, _ := spanAttribute(responseSpan, "gql.request.query")
+	assert.Equal(t, "{name}", query.AsString())
+	document, _ := spanAttribute(responseSpan, "graphql.document")
+	assert.Equal(t, "{name}", document.AsString())
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestWithOperationSpanKindSelector(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(
+		WithTracerProvider(provider),
+		WithOperationSpanKindSelector(func(oc *graphql.OperationContext) trace.SpanKind {
+			if oc.Operation.Operation == ast.Query {
+				return trace.SpanKindInternal
+			}
+			return trace.SpanKindServer
+		}),
+	))
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	spans := endedSpans(spanRecorder)
+	if got, expected := len(spans), 2; got != expected {
+		t.Fatalf("got %d spans, expected %d", got, expected)
+	}
+	// the field span still uses the name based selector
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	assert.Equal(t, trace.SpanKindInternal, spans[1].SpanKind())
+	opType, ok := spanAttribute(spans[1], "graphql.operation.type")
+	assert.True(t, ok)
+	assert.Equal(t, "query", opType.AsString())
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestTruncateDocument(t *testing.T) {
+	assert.Equal(t, "{name}", truncateDocument("{name}", 0))
+	assert.Equal(t, "{name}", truncateDocument("{name}", 6))
+	assert.Equal(t, "{na[TRUNCATED]", truncateDocument("{name}", 3))
+	// the truncation does not split the multi-byte runes
+	assert.Equal(t, `{"é[TRUNCATED]`, truncateDocument(`{"éé"}`, 4))
+}
+
+func TestWithMaxDocumentLength(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithMaxDocumentLength(3)))
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	responseSpan := endedSpans(spanRecorder)[1]
+	query, _ := spanAttribute(responseSpan, requestQueryKey)
+	assert.Equal(t, "{na[TRUNCATED]", query.AsString())
+	length, ok := spanAttribute(responseSpan, requestQueryLengthKey)
+	assert.True(t, ok)
+	assert.Equal(t, int64(len("{name}")), length.AsInt64())
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestWithoutDocument(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(
+		WithTracerProvider(provider),
+		WithAttributeConvention(DuplicateConvention),
+		WithoutDocument(func(oc *graphql.OperationContext) bool {
+			return oc.OperationName == testQueryName
+		}),
+	))
+
+	for _, opName := range []string{testQueryName, "OtherQuery"} {
+		body := strings.NewReader(fmt.Sprintf("{\"operationName\":\"%s\",\"query\":\"query %s { name }\"}", opName, opName))
+		r := httptest.NewRequest("POST", "/foo", body)
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	spans := endedSpans(spanRecorder)
+	assert.Len(t, spans, 4)
+
+	omittedSpan := spans[1]
+	_, ok := spanAttribute(omittedSpan, requestQueryKey)
+	assert.False(t, ok)
+	_, ok = spanAttribute(omittedSpan, "graphql.document")
+	assert.False(t, ok)
+	length, _ := spanAttribute(omittedSpan, requestQueryLengthKey)
+	assert.Equal(t, int64(len("query NamedQuery { name }")), length.AsInt64())
+
+	recordedSpan := spans[3]
+	query, _ := spanAttribute(recordedSpan, requestQueryKey)
+	assert.Equal(t, "query OtherQuery { name }", query.AsString())
+	_, ok = spanAttribute(recordedSpan, requestQueryLengthKey)
+	assert.False(t, ok)
+}
+
+// statsSetter is a handler extension setting the given stats on the operation context.
+type statsSetter struct {
+	name  string
+	stats interface{}
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = statsSetter{}
+
+func (e statsSetter) ExtensionName() string {
+	return e.name
+}
+
+func (e statsSetter) Validate(_ graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (e statsSetter) MutateOperationContext(_ context.Context, oc *graphql.OperationContext) *gqlerror.Error {
+	oc.Stats.SetExtension(e.name, e.stats)
+	return nil
+}
+
 // newMockServer provides a server for use in resolver tests that isn't relying on generated code.
 // It isn't a perfect reproduction of a generated server, but it aims to be good enough to
 // test the handler package without relying on codegen.
@@ -551,7 +988,9 @@ func newMockServer(resolver func(ctx context.Context) (interface{}, error)) *han
 					})
 					res, err := graphql.GetOperationContext(ctx).ResolverMiddleware(ctx, resolver)
 					if err != nil {
-						panic(err)
+						// like the generated code, the returned error is added to the response after the field middlewares
+						graphql.AddError(ctx, err)
+						return &graphql.Response{Data: []byte(`null`)}
 					}
 					return res.(*graphql.Response)
 				}
@@ -630,7 +1069,7 @@ func newMockServerError(resolver func(ctx context.Context) (interface{}, error))
 }
 
 func testSpans(t *testing.T, spanRecorder *tracetest.SpanRecorder, spanName string, spanCode codes.Code, spanKind trace.SpanKind) {
-	spans := spanRecorder.Ended()
+	spans := endedSpans(spanRecorder)
 	if got, expected := len(spans), 2; got != expected {
 		t.Fatalf("got %d spans, expected %d", got, expected)
 	}
@@ -643,8 +1082,31 @@ func testSpans(t *testing.T, spanRecorder *tracetest.SpanRecorder, spanName stri
 		t.Errorf("expected name on span %s; got: %q", spanName, responseSpan.Name())
 	}
 
-	for _, s := range spanRecorder.Ended() {
+	for _, s := range endedSpans(spanRecorder) {
 		assert.Equal(t, spanCode, s.Status().Code)
 		assert.Equal(t, spanKind, s.SpanKind())
 	}
 }
+
+// endedSpans returns the ended operation and field spans, leaving out the phase spans.
+func endedSpans(spanRecorder *tracetest.SpanRecorder) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, s := range spanRecorder.Ended() {
+		switch s.Name() {
+		case readSpanName, parsingSpanName, validationSpanName:
+			continue
+		}
+		spans = append(spans, s)
+	}
+	return spans
+}
+
+// spanAttribute returns the value of the attribute with the given key recorded on the span.
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, a := range span.Attributes() {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
@@ -0,0 +1,120 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+const (
+	operationDurationMetric = "graphql.server.operation.duration"
+	requestCountMetric      = "graphql.server.requests"
+	resolverDurationMetric  = "graphql.server.resolver.duration"
+	persistedQueryMetric    = "graphql.server.persisted_queries"
+	complexityMetric        = "graphql.server.operation.complexity"
+	panicMetric             = "graphql.server.panics"
+)
+
+// durationBuckets are the histogram boundaries, in seconds, used for duration metrics.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+// complexityBuckets are the histogram boundaries used for the operation complexity.
+var complexityBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// instruments holds the metric instruments used by the Tracer.
+type instruments struct {
+	operationDuration   metric.Float64Histogram
+	requestCount        metric.Int64Counter
+	resolverDuration    metric.Float64Histogram
+	persistedQueryCount metric.Int64Counter
+	operationComplexity metric.Int64Histogram
+	panicCount          metric.Int64Counter
+}
+
+func newInstruments(meter metric.Meter) instruments {
+	operationDuration, err := meter.Float64Histogram(
+		operationDurationMetric,
+		metric.WithDescription("Duration of GraphQL operations."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	)
+	if err != nil {
+		otel.Handle(err)
+		operationDuration = noop.Float64Histogram{}
+	}
+
+	requestCount, err := meter.Int64Counter(
+		requestCountMetric,
+		metric.WithDescription("Number of GraphQL requests."),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+		requestCount = noop.Int64Counter{}
+	}
+
+	resolverDuration, err := meter.Float64Histogram(
+		resolverDurationMetric,
+		metric.WithDescription("Duration of GraphQL field resolvers."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...),
+	)
+	if err != nil {
+		otel.Handle(err)
+		resolverDuration = noop.Float64Histogram{}
+	}
+
+	persistedQueryCount, err := meter.Int64Counter(
+		persistedQueryMetric,
+		metric.WithDescription("Number of GraphQL requests using automatic persisted queries, by outcome."),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+		persistedQueryCount = noop.Int64Counter{}
+	}
+
+	operationComplexity, err := meter.Int64Histogram(
+		complexityMetric,
+		metric.WithDescription("Complexity of GraphQL operations, as computed by the complexity extension."),
+		metric.WithUnit("{complexity}"),
+		metric.WithExplicitBucketBoundaries(complexityBuckets...),
+	)
+	if err != nil {
+		otel.Handle(err)
+		operationComplexity = noop.Int64Histogram{}
+	}
+
+	panicCount, err := meter.Int64Counter(
+		panicMetric,
+		metric.WithDescription("Number of panics recovered while handling GraphQL requests."),
+		metric.WithUnit("{panic}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+		panicCount = noop.Int64Counter{}
+	}
+
+	return instruments{
+		operationDuration:   operationDuration,
+		requestCount:        requestCount,
+		resolverDuration:    resolverDuration,
+		persistedQueryCount: persistedQueryCount,
+		operationComplexity: operationComplexity,
+		panicCount:          panicCount,
+	}
+}
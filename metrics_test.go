@@ -0,0 +1,222 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestOperationMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	// metrics must be recorded even when spans are not sampled
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample()))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(tracerProvider), WithMeterProvider(meterProvider)))
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	rm := collectMetrics(t, reader)
+	expectedAttrs := attribute.NewSet(
+		RequestOperationName(namelessQueryName),
+		RequestOperationType("query"),
+		ResponseHasError(false),
+		ResponseOutcome(ResponseOutcomeSuccess),
+	)
+
+	duration, ok := findMetric(rm, operationDurationMetric).Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, duration.DataPoints, 1)
+	assert.Equal(t, uint64(1), duration.DataPoints[0].Count)
+	assert.Equal(t, expectedAttrs, duration.DataPoints[0].Attributes)
+
+	count, ok := findMetric(rm, requestCountMetric).Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, count.DataPoints, 1)
+	assert.Equal(t, int64(1), count.DataPoints[0].Value)
+	assert.Equal(t, expectedAttrs, count.DataPoints[0].Attributes)
+}
+
+func TestOperationMetricsWithError(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(tracetest.NewSpanRecorder()))
+
+	srv := newMockServerError(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(tracerProvider), WithMeterProvider(meterProvider)))
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	rm := collectMetrics(t, reader)
+	count, ok := findMetric(rm, requestCountMetric).Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, count.DataPoints, 1)
+	hasError, _ := count.DataPoints[0].Attributes.Value(responseHasErrorKey)
+	assert.True(t, hasError.AsBool())
+}
+
+func collectMetrics(t *testing.T, reader sdkmetric.Reader) metricdata.ResourceMetrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	return rm
+}
+
+func findMetric(rm metricdata.ResourceMetrics, name string) metricdata.Metrics {
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m
+			}
+		}
+	}
+	return metricdata.Metrics{}
+}
+
+func TestResolverMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample()))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(
+		WithTracerProvider(tracerProvider),
+		WithMeterProvider(meterProvider),
+		WithResolverMetrics(nil),
+	))
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	rm := collectMetrics(t, reader)
+	duration, ok := findMetric(rm, resolverDurationMetric).Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, duration.DataPoints, 1)
+	assert.Equal(t, uint64(1), duration.DataPoints[0].Count)
+	assert.Equal(t, attribute.NewSet(
+		ResolverObject("name"),
+		ResolverField("name"),
+		ResolverHasError(false),
+	), duration.DataPoints[0].Attributes)
+}
+
+func TestResolverMetricsDisabledByDefault(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithMeterProvider(meterProvider)))
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	rm := collectMetrics(t, reader)
+	assert.Empty(t, findMetric(rm, resolverDurationMetric).Name)
+}
+
+func TestResolverMetricsPredicate(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(
+		WithMeterProvider(meterProvider),
+		WithResolverMetrics(func(ctx *graphql.FieldContext) bool {
+			return ctx.IsResolver
+		}),
+	))
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	rm := collectMetrics(t, reader)
+	assert.Empty(t, findMetric(rm, resolverDurationMetric).Name)
+}
+
+func TestComplexityMetric(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithMeterProvider(meterProvider)))
+	srv.Use(statsSetter{name: complexityLimit, stats: &extension.ComplexityStats{Complexity: 3, ComplexityLimit: 10}})
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+	rm := collectMetrics(t, reader)
+	complexity, ok := findMetric(rm, complexityMetric).Data.(metricdata.Histogram[int64])
+	require.True(t, ok)
+	require.Len(t, complexity.DataPoints, 1)
+	dp := complexity.DataPoints[0]
+	assert.Equal(t, uint64(1), dp.Count)
+	assert.Equal(t, int64(3), dp.Sum)
+	opType, _ := dp.Attributes.Value(semconv.GraphqlOperationTypeKey)
+	assert.Equal(t, "query", opType.AsString())
+}
+
+func TestComplexityMetricWithoutComplexityExtension(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithMeterProvider(meterProvider)))
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+	rm := collectMetrics(t, reader)
+	assert.Nil(t, findMetric(rm, complexityMetric).Data)
+}
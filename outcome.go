@@ -0,0 +1,71 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"bytes"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Outcomes of a response.
+const (
+	// ResponseOutcomeSuccess is a response without errors.
+	ResponseOutcomeSuccess = "success"
+	// ResponseOutcomePartial is a response with both data and errors, e.g. when a nullable field failed.
+	ResponseOutcomePartial = "partial"
+	// ResponseOutcomeFailure is a response with errors and null data, e.g. for a rejected request.
+	ResponseOutcomeFailure = "failure"
+)
+
+// StatusPolicyFunc is the signature of the function deciding the status code of the operation span
+// from the outcome of a response with failing errors, e.g. to not fail the partial successes.
+// Returning codes.Unset leaves the status unset.
+type StatusPolicyFunc func(outcome string) codes.Code
+
+// DefaultStatusPolicy sets the status of the operation span to Error for both the partial successes and the failures.
+func DefaultStatusPolicy(_ string) codes.Code {
+	return codes.Error
+}
+
+// PartialSuccessStatusPolicy sets the status of the operation span to the given code for the partial successes,
+// and to Error for the failures.
+func PartialSuccessStatusPolicy(code codes.Code) StatusPolicyFunc {
+	return func(outcome string) codes.Code {
+		if outcome == ResponseOutcomePartial {
+			return code
+		}
+		return codes.Error
+	}
+}
+
+// responseOutcome returns the outcome of the response from its errors and whether its data is null.
+func responseOutcome(resp *graphql.Response) string {
+	switch {
+	case resp == nil || len(resp.Errors) == 0:
+		return ResponseOutcomeSuccess
+	case hasData(resp):
+		return ResponseOutcomePartial
+	default:
+		return ResponseOutcomeFailure
+	}
+}
+
+// hasData returns whether the data of the response is neither absent nor null.
+func hasData(resp *graphql.Response) bool {
+	data := bytes.TrimSpace(resp.Data)
+	return len(data) > 0 && !bytes.Equal(data, []byte("null"))
+}
@@ -0,0 +1,118 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestResponseOutcome(t *testing.T) {
+	errList := gqlerror.List{gqlerror.Errorf("boom")}
+
+	tests := []struct {
+		name string
+		resp *graphql.Response
+		want string
+	}{
+		{name: "no response", want: ResponseOutcomeSuccess},
+		{name: "data", resp: &graphql.Response{Data: []byte(`{"name":"test"}`)}, want: ResponseOutcomeSuccess},
+		{name: "data and errors", resp: &graphql.Response{Data: []byte(`{"name":null}`), Errors: errList}, want: ResponseOutcomePartial},
+		{name: "null data and errors", resp: &graphql.Response{Data: []byte(" null "), Errors: errList}, want: ResponseOutcomeFailure},
+		{name: "errors only", resp: &graphql.Response{Errors: errList}, want: ResponseOutcomeFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, responseOutcome(tt.resp))
+		})
+	}
+}
+
+func TestPartialSuccessStatusPolicy(t *testing.T) {
+	policy := PartialSuccessStatusPolicy(codes.Unset)
+
+	assert.Equal(t, codes.Unset, policy(ResponseOutcomePartial))
+	assert.Equal(t, codes.Error, policy(ResponseOutcomeFailure))
+}
+
+func TestWithStatusPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		policy      StatusPolicyFunc
+		wantOutcome string
+		wantCode    codes.Code
+	}{
+		{name: "partial default", data: []byte(`{"name":"test"}`), wantOutcome: ResponseOutcomePartial, wantCode: codes.Error},
+		{name: "failure default", wantOutcome: ResponseOutcomeFailure, wantCode: codes.Error},
+		{name: "partial ok", data: []byte(`{"name":"test"}`), policy: PartialSuccessStatusPolicy(codes.Ok), wantOutcome: ResponseOutcomePartial, wantCode: codes.Ok},
+		{name: "partial unset", data: []byte(`{"name":"test"}`), policy: PartialSuccessStatusPolicy(codes.Unset), wantOutcome: ResponseOutcomePartial, wantCode: codes.Unset},
+		{name: "failure", policy: PartialSuccessStatusPolicy(codes.Ok), wantOutcome: ResponseOutcomeFailure, wantCode: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spanRecorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+			srv := newMockServerError(func(_ context.Context) (interface{}, error) {
+				return &graphql.Response{Data: tt.data}, nil
+			})
+			srv.Use(Middleware(WithTracerProvider(provider), WithStatusPolicy(tt.policy)))
+
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+			spans := endedSpans(spanRecorder)
+			require.Len(t, spans, 2)
+			assert.Equal(t, codes.Error, spans[0].Status().Code, "the field span is not affected by the policy")
+			assert.Equal(t, tt.wantCode, spans[1].Status().Code)
+			outcome, ok := spanAttribute(spans[1], responseOutcomeKey)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantOutcome, outcome.AsString())
+		})
+	}
+}
+
+func TestOutcomeMetric(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(tracetest.NewSpanRecorder()))
+
+	srv := newMockServerError(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(tracerProvider), WithMeterProvider(meterProvider)))
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+	rm := collectMetrics(t, reader)
+	count, ok := findMetric(rm, requestCountMetric).Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, count.DataPoints, 1)
+	outcome, _ := count.DataPoints[0].Attributes.Value(responseOutcomeKey)
+	assert.Equal(t, ResponseOutcomePartial, outcome.AsString())
+}
@@ -0,0 +1,62 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// RecoverFunc wraps a recover function, e.g. for handler.SetRecoverFunc, to record the panics
+// not already recorded on a field span: the panic value and stack trace are recorded as an exception event
+// on the active span, which is marked as Error, and the panic counter is incremented.
+// A nil next defaults to graphql.DefaultRecover.
+//
+//	tracer := otelgqlgen.Middleware()
+//	srv.Use(tracer)
+//	srv.SetRecoverFunc(tracer.RecoverFunc(nil))
+func (a Tracer) RecoverFunc(next graphql.RecoverFunc) graphql.RecoverFunc {
+	if next == nil {
+		next = graphql.DefaultRecover
+	}
+	return func(ctx context.Context, err any) error {
+		if !takeRecordedPanic(ctx, graphql.GetFieldContext(ctx)) {
+			a.recordPanic(ctx, oteltrace.SpanFromContext(ctx), err)
+		}
+		return next(ctx, err)
+	}
+}
+
+// recordPanic records the panic on the span, if it is recording, and counts it.
+func (a Tracer) recordPanic(ctx context.Context, span oteltrace.Span, value any, attrs ...attribute.KeyValue) {
+	a.instruments.panicCount.Add(ctx, 1, metric.WithAttributes(attrs...))
+	if !span.IsRecording() {
+		return
+	}
+	span.AddEvent(semconv.ExceptionEventName, oteltrace.WithAttributes(
+		semconv.ExceptionType(fmt.Sprintf("%T", value)),
+		semconv.ExceptionMessage(fmt.Sprint(value)),
+		semconv.ExceptionStacktrace(string(debug.Stack())),
+	))
+	span.SetStatus(codes.Error, fmt.Sprintf("panic: %v", value))
+}
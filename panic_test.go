@@ -0,0 +1,197 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestFieldPanic(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	srv := newMockPanicServer(true)
+	tracer := Middleware(WithTracerProvider(provider), WithMeterProvider(meterProvider))
+	srv.Use(tracer)
+	var recovered any
+	srv.SetRecoverFunc(tracer.RecoverFunc(func(_ context.Context, err any) error {
+		recovered = err
+		return gqlerror.Errorf("internal system error")
+	}))
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+	assert.Equal(t, "resolver panic", recovered)
+
+	spans := endedSpans(spanRecorder)
+	require.Len(t, spans, 2)
+	fieldSpan, rootSpan := spans[0], spans[1]
+	assert.Equal(t, codes.Error, fieldSpan.Status().Code)
+	assert.Equal(t, "panic: resolver panic", fieldSpan.Status().Description)
+	require.Len(t, fieldSpan.Events(), 1)
+	event := fieldSpan.Events()[0]
+	assert.Equal(t, semconv.ExceptionEventName, event.Name)
+	assert.Contains(t, event.Attributes, semconv.ExceptionType("string"))
+	assert.Contains(t, event.Attributes, semconv.ExceptionMessage("resolver panic"))
+	stacktrace := attribute.NewSet(event.Attributes...)
+	stack, ok := stacktrace.Value(semconv.ExceptionStacktraceKey)
+	assert.True(t, ok)
+	assert.Contains(t, stack.AsString(), "newMockPanicServer")
+
+	// the panic recorded on the field span is not recorded again by the recover func
+	for _, event := range rootSpan.Events() {
+		assert.NotContains(t, event.Attributes, semconv.ExceptionMessage("resolver panic"))
+	}
+
+	rm := collectMetrics(t, reader)
+	panics, ok := findMetric(rm, panicMetric).Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, panics.DataPoints, 1)
+	assert.Equal(t, int64(1), panics.DataPoints[0].Value)
+	object, _ := panics.DataPoints[0].Attributes.Value(resolverObjectKey)
+	assert.Equal(t, "Query", object.AsString())
+}
+
+func TestFieldPanicNotSampled(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample()))
+
+	// without RecoverFunc, the panic is only counted by the field interceptor
+	srv := newMockPanicServer(true)
+	srv.Use(Middleware(WithTracerProvider(provider), WithMeterProvider(meterProvider)))
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+	rm := collectMetrics(t, reader)
+	panics, ok := findMetric(rm, panicMetric).Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, panics.DataPoints, 1)
+	assert.Equal(t, int64(1), panics.DataPoints[0].Value)
+	assert.Equal(t, attribute.NewSet(ResolverObject("Query"), ResolverField("name")), panics.DataPoints[0].Attributes)
+}
+
+func TestRecoverFunc(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	srv := newMockPanicServer(false)
+	tracer := Middleware(WithTracerProvider(provider), WithMeterProvider(meterProvider))
+	srv.Use(tracer)
+	srv.SetRecoverFunc(tracer.RecoverFunc(func(_ context.Context, _ any) error {
+		return gqlerror.Errorf("internal system error")
+	}))
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+	spans := endedSpans(spanRecorder)
+	require.Len(t, spans, 1)
+	// the panic is recorded on the operation span, along with the error it is turned into
+	var panicEvents int
+	for _, event := range spans[0].Events() {
+		attrs := attribute.NewSet(event.Attributes...)
+		if _, ok := attrs.Value(semconv.ExceptionStacktraceKey); ok {
+			panicEvents++
+			assert.Contains(t, event.Attributes, semconv.ExceptionMessage("handler panic"))
+		}
+	}
+	assert.Equal(t, 1, panicEvents)
+
+	rm := collectMetrics(t, reader)
+	panics, ok := findMetric(rm, panicMetric).Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, panics.DataPoints, 1)
+	assert.Equal(t, int64(1), panics.DataPoints[0].Value)
+}
+
+// newMockPanicServer provides a server panicking in the resolver of the name field, or else in the response handler,
+// recovering the panics like the generated code does.
+func newMockPanicServer(inField bool) *handler.Server {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Query {
+			name: String!
+		}
+	`})
+	srv := handler.New(&graphql.ExecutableSchemaMock{
+		ExecFunc: func(_ context.Context) graphql.ResponseHandler {
+			ran := false
+			return func(ctx context.Context) (resp *graphql.Response) {
+				if ran {
+					return nil
+				}
+				ran = true
+				oc := graphql.GetOperationContext(ctx)
+				if !inField {
+					defer func() {
+						if r := recover(); r != nil {
+							graphql.AddError(ctx, oc.Recover(ctx, r))
+							resp = &graphql.Response{Errors: graphql.GetErrors(ctx)}
+						}
+					}()
+					panic("handler panic")
+				}
+
+				ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{
+					Object: "Query",
+					Field: graphql.CollectedField{
+						Field: &ast.Field{
+							Name:             "name",
+							Definition:       schema.Types["Query"].Fields.ForName("name"),
+							ObjectDefinition: schema.Types["Query"],
+						},
+					},
+				})
+				defer func() {
+					if r := recover(); r != nil {
+						graphql.AddError(ctx, oc.Recover(ctx, r))
+						resp = &graphql.Response{Errors: graphql.GetErrors(ctx)}
+					}
+				}()
+				_, _ = oc.ResolverMiddleware(ctx, func(_ context.Context) (interface{}, error) {
+					panic("resolver panic")
+				})
+				return &graphql.Response{Data: []byte(`{"name":"test"}`)}
+			}
+		},
+		SchemaFunc: func() *ast.Schema {
+			return schema
+		},
+	})
+	srv.AddTransport(&transport.GET{})
+
+	return srv
+}
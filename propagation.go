@@ -0,0 +1,124 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// extractExtensionsContext returns the context parented to the trace context sent in the
+// configured key of the request extensions. The incoming context is returned as is
+// when the extensions do not carry a valid trace context.
+func (a Tracer) extractExtensionsContext(ctx context.Context, oc *graphql.OperationContext) context.Context {
+	if a.extensionsPropagationKey == "" {
+		return ctx
+	}
+	carrier, ok := oc.Extensions[a.extensionsPropagationKey].(map[string]any)
+	if !ok {
+		return ctx
+	}
+
+	extracted := a.propagators.Extract(ctx, mapCarrier(carrier))
+	if !oteltrace.SpanContextFromContext(extracted).IsValid() {
+		return ctx
+	}
+	return extracted
+}
+
+// setResponseTraceExtensions adds the trace ID and the traceparent of the operation
+// to the response extensions, if enabled and allowed by the predicate.
+// The traces dropped by the sampler are left out, as they cannot be looked up.
+func (a Tracer) setResponseTraceExtensions(ctx context.Context, spanContext oteltrace.SpanContext, resp *graphql.Response) {
+	if a.responseTraceIDKey == "" && a.responseTraceParentKey == "" {
+		return
+	}
+	if resp == nil || !spanContext.IsValid() || !spanContext.IsSampled() {
+		return
+	}
+	if a.responseTracePredicate != nil && !a.responseTracePredicate(ctx, resp) {
+		return
+	}
+
+	if resp.Extensions == nil {
+		resp.Extensions = make(map[string]interface{})
+	}
+	if a.responseTraceIDKey != "" {
+		resp.Extensions[a.responseTraceIDKey] = spanContext.TraceID().String()
+	}
+	if a.responseTraceParentKey != "" {
+		carrier := mapCarrier{}
+		propagation.TraceContext{}.Inject(oteltrace.ContextWithSpanContext(ctx, spanContext), carrier)
+		resp.Extensions[a.responseTraceParentKey] = carrier.Get("traceparent")
+	}
+}
+
+// OnlyErrorResponses returns a ResponsePredicateFunc matching the responses with errors.
+func OnlyErrorResponses() ResponsePredicateFunc {
+	return func(_ context.Context, resp *graphql.Response) bool {
+		return len(resp.Errors) > 0
+	}
+}
+
+// WebsocketInitFunc returns a websocket InitFunc that extracts the trace context and baggage
+// sent in the connection_init payload, e.g. {"traceparent": "00-..."}, with the propagators of the Tracer.
+// Browsers cannot set headers on websocket upgrades, so this lets the operations of the connection
+// join the client trace. next, which may be nil, is called with the extracted context.
+// example:
+//
+//	tracer := otelgqlgen.Middleware()
+//	srv.Use(tracer)
+//	srv.AddTransport(transport.Websocket{
+//		InitFunc: tracer.WebsocketInitFunc(nil),
+//	})
+func (a Tracer) WebsocketInitFunc(next transport.WebsocketInitFunc) transport.WebsocketInitFunc {
+	return func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		ctx = a.propagators.Extract(ctx, mapCarrier(initPayload))
+		if next == nil {
+			return ctx, nil, nil
+		}
+		return next(ctx, initPayload)
+	}
+}
+
+// mapCarrier adapts a JSON object, such as a connection_init payload or a request extension,
+// to a propagation.TextMapCarrier.
+type mapCarrier map[string]any
+
+var _ propagation.TextMapCarrier = mapCarrier{}
+
+// Get returns the string value associated with the passed key.
+func (c mapCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+// Set stores the key-value pair.
+func (c mapCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+// Keys lists the keys stored in this carrier.
+func (c mapCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
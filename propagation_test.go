@@ -0,0 +1,207 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testTraceParent = "00-" + testTraceID + "-00f067aa0ba902b7-01"
+)
+
+func TestWebsocketInitFunc(t *testing.T) {
+	var nextCtx context.Context
+	tracer := Middleware(WithPropagators(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})))
+	initFunc := tracer.WebsocketInitFunc(func(ctx context.Context, _ transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		nextCtx = ctx
+		return ctx, nil, nil
+	})
+
+	ctx, _, err := initFunc(context.Background(), transport.InitPayload{
+		"traceparent": testTraceParent,
+		"baggage":     "tenant=acme",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ctx, nextCtx)
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	assert.True(t, spanContext.IsRemote())
+	assert.Equal(t, testTraceID, spanContext.TraceID().String())
+	assert.Equal(t, "acme", baggage.FromContext(ctx).Member("tenant").Value())
+}
+
+func TestWebsocketInitFuncWithoutTraceContext(t *testing.T) {
+	initFunc := Middleware(WithPropagators(propagation.TraceContext{})).WebsocketInitFunc(nil)
+
+	ctx, initAckPayload, err := initFunc(context.Background(), transport.InitPayload{"Authorization": "token"})
+	require.NoError(t, err)
+	assert.Nil(t, initAckPayload)
+	assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+}
+
+func TestExtensionsPropagation(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(
+		WithTracerProvider(provider),
+		WithPropagators(propagation.TraceContext{}),
+		WithExtensionsPropagation("traceContext"),
+	))
+
+	body := strings.NewReader(`{"query":"{ name }","extensions":{"traceContext":{"traceparent":"` + testTraceParent + `"}}}`)
+	r := httptest.NewRequest("POST", "/foo", body)
+	r.Header.Set("Content-Type", "application/json")
+	r = r.WithContext(trace.ContextWithSpanContext(r.Context(), newTestSpanContext(t)))
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	spans := endedSpans(spanRecorder)
+	require.Len(t, spans, 2)
+	responseSpan := spans[1]
+	assert.Equal(t, testTraceID, responseSpan.SpanContext().TraceID().String())
+	assert.True(t, responseSpan.Parent().IsRemote())
+	assert.Equal(t, responseSpan.SpanContext().TraceID(), spans[0].SpanContext().TraceID())
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestExtensionsPropagationFallback(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(
+		WithTracerProvider(provider),
+		WithPropagators(propagation.TraceContext{}),
+		WithExtensionsPropagation("traceContext"),
+	))
+
+	body := strings.NewReader(`{"query":"{ name }","extensions":{"traceContext":{"traceparent":"invalid"}}}`)
+	r := httptest.NewRequest("POST", "/foo", body)
+	r.Header.Set("Content-Type", "application/json")
+	incoming := newTestSpanContext(t)
+	r = r.WithContext(trace.ContextWithSpanContext(r.Context(), incoming))
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	spans := endedSpans(spanRecorder)
+	require.Len(t, spans, 2)
+	assert.Equal(t, incoming.TraceID(), spans[1].SpanContext().TraceID())
+	assert.Equal(t, incoming.SpanID(), spans[1].Parent().SpanID())
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func newTestSpanContext(t *testing.T) trace.SpanContext {
+	t.Helper()
+	traceID, err := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("b7ad6b7169203331")
+	require.NoError(t, err)
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+}
+
+func TestResponseTraceID(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithResponseTraceID(""), WithResponseTraceParent("")))
+
+	r := httptest.NewRequest("GET", "/foo?query={name}", nil)
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	var body struct {
+		Extensions map[string]string `json:"extensions"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	spanContext := endedSpans(spanRecorder)[1].SpanContext()
+	assert.Equal(t, spanContext.TraceID().String(), body.Extensions["traceId"])
+	assert.Equal(t, "00-"+spanContext.TraceID().String()+"-"+spanContext.SpanID().String()+"-01", body.Extensions["traceparent"])
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestResponseTraceIDNotSampled(t *testing.T) {
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample()))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithResponseTraceID(""), WithResponseTraceParent("")))
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+	assert.NotContains(t, w.Body.String(), "traceId")
+	assert.NotContains(t, w.Body.String(), "traceparent")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestResponseTraceIDOnlyErrorResponses(t *testing.T) {
+	provider := sdktrace.NewTracerProvider()
+	opts := []Option{WithTracerProvider(provider), WithResponseTraceID("requestTrace"), WithResponseTracePredicate(OnlyErrorResponses())}
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(opts...))
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/foo?query={name}", nil))
+	assert.NotContains(t, w.Body.String(), "requestTrace")
+
+	srv = newMockServerError(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(opts...))
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/foo?query={name}", nil))
+	assert.Contains(t, w.Body.String(), `"requestTrace":"`)
+}
@@ -0,0 +1,165 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const defaultRedactionMarker = "[REDACTED]"
+
+// RedactionPolicy describes the request variables whose values must not be recorded.
+// Paths are dotted, e.g. "input.password"; elements of lists share the path of the list.
+type RedactionPolicy struct {
+	// Patterns redacts the variables and input fields whose name matches any of the patterns.
+	Patterns []*regexp.Regexp
+	// Paths redacts the variables and input fields at the given paths.
+	Paths []string
+	// Allow, if not empty, redacts everything but the given paths and their descendants.
+	Allow []string
+	// Marker replaces the redacted values. It defaults to "[REDACTED]".
+	Marker string
+}
+
+// redactor applies a RedactionPolicy.
+type redactor struct {
+	policy RedactionPolicy
+	paths  map[string]struct{}
+}
+
+func newRedactor(policy RedactionPolicy) *redactor {
+	if policy.Marker == "" {
+		policy.Marker = defaultRedactionMarker
+	}
+	paths := make(map[string]struct{}, len(policy.Paths))
+	for _, p := range policy.Paths {
+		paths[p] = struct{}{}
+	}
+	return &redactor{policy: policy, paths: paths}
+}
+
+// withPaths returns a redactor also redacting the given paths.
+// It can be called on a nil redactor.
+func (r *redactor) withPaths(paths []string) *redactor {
+	if len(paths) == 0 {
+		return r
+	}
+	var policy RedactionPolicy
+	if r != nil {
+		policy = r.policy
+	}
+	policy.Paths = append(slices.Clone(policy.Paths), paths...)
+	return newRedactor(policy)
+}
+
+// marker returns the redaction marker. It can be called on a nil redactor.
+func (r *redactor) marker() string {
+	if r == nil {
+		return defaultRedactionMarker
+	}
+	return r.policy.Marker
+}
+
+// redactVariables returns a copy of the variables with the redacted values replaced by the marker,
+// and the sorted paths of the redacted values.
+func (r *redactor) redactVariables(variables map[string]interface{}) (map[string]interface{}, []string) {
+	if r == nil {
+		return variables, nil
+	}
+
+	var redacted []string
+	result := r.redactObject(variables, "", len(r.policy.Allow) > 0, &redacted)
+	// elements of lists share the path of their list, so the paths are deduplicated
+	sort.Strings(redacted)
+	return result, slices.Compact(redacted)
+}
+
+func (r *redactor) redactObject(object map[string]interface{}, prefix string, checkAllow bool, redacted *[]string) map[string]interface{} {
+	result := make(map[string]interface{}, len(object))
+	for k, v := range object {
+		result[k] = r.redactValue(v, k, prefix+k, checkAllow, redacted)
+	}
+	return result
+}
+
+func (r *redactor) redactValue(value interface{}, name, path string, checkAllow bool, redacted *[]string) interface{} {
+	if r.denied(name, path) {
+		*redacted = append(*redacted, path)
+		return r.policy.Marker
+	}
+	if checkAllow {
+		switch {
+		case r.allowed(path):
+			checkAllow = false
+		case !r.allowedDescendant(path):
+			*redacted = append(*redacted, path)
+			return r.policy.Marker
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return r.redactObject(v, path+".", checkAllow, redacted)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = r.redactValue(item, name, path, checkAllow, redacted)
+		}
+		return list
+	default:
+		if checkAllow {
+			// a leaf value that is only on the way to an allowed path
+			*redacted = append(*redacted, path)
+			return r.policy.Marker
+		}
+		return v
+	}
+}
+
+// denied reports whether the value is redacted by the patterns or the paths.
+func (r *redactor) denied(name, path string) bool {
+	if _, ok := r.paths[path]; ok {
+		return true
+	}
+	for _, pattern := range r.policy.Patterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed reports whether the path is allowed, along with its descendants.
+func (r *redactor) allowed(path string) bool {
+	for _, allow := range r.policy.Allow {
+		if path == allow || strings.HasPrefix(path, allow+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedDescendant reports whether the path leads to an allowed path.
+func (r *redactor) allowedDescendant(path string) bool {
+	for _, allow := range r.policy.Allow {
+		if strings.HasPrefix(allow, path+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRedactVariables(t *testing.T) {
+	variables := map[string]interface{}{
+		"login": "john",
+		"input": map[string]interface{}{
+			"password": "secret",
+			"profile": map[string]interface{}{
+				"name":  "John",
+				"phone": "555",
+			},
+		},
+		"tokens": []interface{}{
+			map[string]interface{}{"accessToken": "a", "scope": "read"},
+			map[string]interface{}{"accessToken": "b", "scope": "write"},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		policy           RedactionPolicy
+		expected         map[string]interface{}
+		expectedRedacted []string
+	}{
+		{
+			name:   "patterns",
+			policy: RedactionPolicy{Patterns: []*regexp.Regexp{regexp.MustCompile(`(?i)password|token`)}},
+			expected: map[string]interface{}{
+				"login": "john",
+				"input": map[string]interface{}{
+					"password": "[REDACTED]",
+					"profile":  map[string]interface{}{"name": "John", "phone": "555"},
+				},
+				"tokens": "[REDACTED]",
+			},
+			expectedRedacted: []string{"input.password", "tokens"},
+		},
+		{
+			name:   "paths",
+			policy: RedactionPolicy{Paths: []string{"input.profile.phone", "tokens.accessToken"}, Marker: "***"},
+			expected: map[string]interface{}{
+				"login": "john",
+				"input": map[string]interface{}{
+					"password": "secret",
+					"profile":  map[string]interface{}{"name": "John", "phone": "***"},
+				},
+				"tokens": []interface{}{
+					map[string]interface{}{"accessToken": "***", "scope": "read"},
+					map[string]interface{}{"accessToken": "***", "scope": "write"},
+				},
+			},
+			expectedRedacted: []string{"input.profile.phone", "tokens.accessToken"},
+		},
+		{
+			name:   "allow list",
+			policy: RedactionPolicy{Allow: []string{"login", "input.profile.name", "tokens.scope"}},
+			expected: map[string]interface{}{
+				"login": "john",
+				"input": map[string]interface{}{
+					"password": "[REDACTED]",
+					"profile":  map[string]interface{}{"name": "John", "phone": "[REDACTED]"},
+				},
+				"tokens": []interface{}{
+					map[string]interface{}{"accessToken": "[REDACTED]", "scope": "read"},
+					map[string]interface{}{"accessToken": "[REDACTED]", "scope": "write"},
+				},
+			},
+			expectedRedacted: []string{"input.password", "input.profile.phone", "tokens.accessToken"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redactedVariables, redacted := newRedactor(tt.policy).redactVariables(variables)
+			assert.Equal(t, tt.expected, redactedVariables)
+			assert.Equal(t, tt.expectedRedacted, redacted)
+		})
+	}
+
+	// the original variables are left untouched
+	assert.Equal(t, "secret", variables["input"].(map[string]interface{})["password"])
+}
+
+func TestRedactionPolicy(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	var resolverVariables map[string]interface{}
+	srv := newMockServer(func(ctx context.Context) (interface{}, error) {
+		resolverVariables = graphql.GetOperationContext(ctx).Variables
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithRedactionPolicy(RedactionPolicy{
+		Patterns: []*regexp.Regexp{regexp.MustCompile(`^id$`)},
+	})))
+
+	body := strings.NewReader("{\"variables\":{\"id\":1},\"query\":\"query ($id: Int!) {\\n  find(id: $id)\\n}\\n\"}")
+	r := httptest.NewRequest("POST", "/foo", body)
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	testSpans(t, spanRecorder, namelessQueryName, codes.Ok, trace.SpanKindServer)
+
+	responseSpan := endedSpans(spanRecorder)[1]
+	id, _ := spanAttribute(responseSpan, "gql.request.variables.id")
+	assert.Equal(t, "[REDACTED]", id.AsString())
+	redacted, _ := spanAttribute(responseSpan, "gql.request.variables.redacted")
+	assert.Equal(t, []string{"id"}, redacted.AsStringSlice())
+	assert.EqualValues(t, 1, resolverVariables["id"])
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
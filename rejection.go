@@ -0,0 +1,93 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"slices"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const complexityLimitExceededCode = "COMPLEXITY_LIMIT_EXCEEDED"
+
+// Phases rejecting a request before its execution.
+const (
+	// RejectionPhaseRequest is a request rejected before its operation was read, e.g. for a malformed body.
+	RejectionPhaseRequest = "request"
+	// RejectionPhasePersistedQuery is a request rejected by the automatic persisted queries.
+	RejectionPhasePersistedQuery = "persisted_query"
+	// RejectionPhaseParse is a request whose query could not be parsed.
+	RejectionPhaseParse = "parse"
+	// RejectionPhaseValidate is a request whose operation or variables are invalid.
+	RejectionPhaseValidate = "validate"
+	// RejectionPhaseComplexity is a request whose operation exceeds the complexity limit.
+	RejectionPhaseComplexity = "complexity"
+)
+
+// rejectionPhase returns the phase that rejected the request before its execution,
+// or an empty string if the request was executed.
+// The phase is found from the codes of the response errors, or else from how far the operation context was built.
+func rejectionPhase(oc *graphql.OperationContext, resp *graphql.Response) string {
+	if resp == nil || len(resp.Errors) == 0 {
+		return ""
+	}
+	for _, err := range resp.Errors {
+		switch errorCode(err) {
+		case persistedQueryNotFoundCode:
+			return RejectionPhasePersistedQuery
+		case errcode.ParseFailed:
+			return RejectionPhaseParse
+		case errcode.ValidationFailed:
+			return RejectionPhaseValidate
+		case complexityLimitExceededCode:
+			return RejectionPhaseComplexity
+		}
+	}
+
+	switch {
+	case oc.Doc == nil && oc.Stats.Parsing.Start.IsZero():
+		// rejected by the transport or by an operation parameter mutator.
+		// The extensions are only set on the operation context once the mutators succeeded,
+		// so the persisted query errors without code are recognized by their message.
+		if hasPersistedQueryError(resp.Errors) {
+			return RejectionPhasePersistedQuery
+		}
+		return RejectionPhaseRequest
+	case oc.Doc == nil:
+		return RejectionPhaseParse
+	case oc.Operation == nil:
+		return RejectionPhaseValidate
+	default:
+		return ""
+	}
+}
+
+// errorCode returns the code of the error, set with errcode.Set.
+func errorCode(err *gqlerror.Error) string {
+	code, _ := err.Extensions["code"].(string)
+	return code
+}
+
+// hasPersistedQueryError reports whether any of the errors is returned by extension.AutomaticPersistedQuery.
+func hasPersistedQueryError(errList gqlerror.List) bool {
+	for _, err := range errList {
+		if slices.Contains(persistedQueryErrorMessages, err.Message) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRejectedRequests(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		limitComplexity bool
+		wantQuery       string
+		wantPhase       string
+	}{
+		{
+			name:      "malformed body",
+			body:      `{`,
+			wantPhase: RejectionPhaseRequest,
+		},
+		{
+			name:      "persisted query not found",
+			body:      `{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"abc"}}}`,
+			wantPhase: RejectionPhasePersistedQuery,
+		},
+		{
+			name:      "persisted query hash mismatch",
+			body:      `{"query":"{ name }","extensions":{"persistedQuery":{"version":1,"sha256Hash":"abc"}}}`,
+			wantPhase: RejectionPhasePersistedQuery,
+		},
+		{
+			name:      "persisted query version",
+			body:      `{"extensions":{"persistedQuery":{"version":2,"sha256Hash":"abc"}}}`,
+			wantPhase: RejectionPhasePersistedQuery,
+		},
+		{
+			name:      "parse",
+			body:      `{"query":"{ name"}`,
+			wantQuery: "{ name",
+			wantPhase: RejectionPhaseParse,
+		},
+		{
+			name:      "validate",
+			body:      `{"query":"{ unknown }"}`,
+			wantQuery: "{ unknown }",
+			wantPhase: RejectionPhaseValidate,
+		},
+		{
+			name:      "operation not found",
+			body:      `{"query":"query A { name }","operationName":"B"}`,
+			wantQuery: "query A { name }",
+			wantPhase: RejectionPhaseValidate,
+		},
+		{
+			name:            "complexity",
+			body:            `{"query":"{ name }"}`,
+			limitComplexity: true,
+			wantQuery:       "{ name }",
+			wantPhase:       RejectionPhaseComplexity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spanRecorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+			srv := newMockServer(func(_ context.Context) (interface{}, error) {
+				return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+			})
+			srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New[string](10)})
+			if tt.limitComplexity {
+				// the mock schema fields have no complexity, so any operation exceeds a negative limit
+				srv.Use(extension.FixedComplexityLimit(-1))
+			}
+			srv.Use(Middleware(WithTracerProvider(provider)))
+
+			r := httptest.NewRequest("POST", "/foo", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			srv.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := endedSpans(spanRecorder)
+			require.Len(t, spans, 1)
+			span := spans[0]
+			assert.Equal(t, codes.Error, span.Status().Code)
+			phase, ok := spanAttribute(span, requestRejectionPhaseKey)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantPhase, phase.AsString())
+			query, _ := spanAttribute(span, requestQueryKey)
+			assert.Equal(t, tt.wantQuery, query.AsString())
+			errorCount, _ := spanAttribute(span, resolverErrorCountKey)
+			assert.Equal(t, int64(1), errorCount.AsInt64())
+		})
+	}
+}
+
+func TestRejectedRequestWithoutOperationContext(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServer(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider)))
+	probe := &operationContextProbe{}
+	srv.Use(probe)
+
+	r := httptest.NewRequest("POST", "/foo", strings.NewReader(`{`))
+	r.Header.Set("Content-Type", "application/json")
+	srv.ServeHTTP(httptest.NewRecorder(), r)
+
+	// the empty operation context of the span is not seen by the next interceptors
+	require.True(t, probe.called)
+	assert.False(t, probe.hasOperationContext)
+	spans := endedSpans(spanRecorder)
+	require.Len(t, spans, 1)
+	assert.Equal(t, spans[0].SpanContext(), probe.spanContext)
+}
+
+// operationContextProbe records whether the response it intercepts has an operation context.
+type operationContextProbe struct {
+	called              bool
+	hasOperationContext bool
+	spanContext         trace.SpanContext
+}
+
+func (p *operationContextProbe) ExtensionName() string {
+	return "OperationContextProbe"
+}
+
+func (p *operationContextProbe) Validate(_ graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (p *operationContextProbe) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	p.called = true
+	p.hasOperationContext = graphql.HasOperationContext(ctx)
+	p.spanContext = trace.SpanContextFromContext(ctx)
+	return next(ctx)
+}
+
+func TestExecutedRequestNotRejected(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockServerError(func(_ context.Context) (interface{}, error) {
+		return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+	})
+	srv.Use(Middleware(WithTracerProvider(provider)))
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo?query={name}", nil))
+
+	for _, span := range endedSpans(spanRecorder) {
+		_, ok := spanAttribute(span, requestRejectionPhaseKey)
+		assert.False(t, ok)
+	}
+}
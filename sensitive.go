@@ -0,0 +1,236 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const defaultSensitiveDirective = "sensitive"
+
+// resolverArgs returns the resolver args attributes,
+// redacting the arguments and input fields marked with the sensitive directive.
+// With WithResolvedArgs, the values received by the resolver are recorded instead,
+// also redacted according to the redaction policy.
+func (a Tracer) resolverArgs(fc *graphql.FieldContext) []attribute.KeyValue {
+	if a.resolvedArgs {
+		args, unresolved := a.normalizeArgs(fc.Field.Definition, fc.Args)
+		sensitivePaths := a.sensitiveArgPaths(fc.Field.Definition)
+		// the sensitive input fields of the args whose input fields could not be resolved
+		// cannot be found by path, so these args are redacted as a whole
+		for _, path := range sensitivePaths {
+			if name, _, ok := strings.Cut(path, "."); ok && slices.Contains(unresolved, name) {
+				sensitivePaths = append(sensitivePaths, name)
+			}
+		}
+		args, _ = a.redactor.withPaths(sensitivePaths).redactVariables(args)
+		return ResolvedResolverArgs(args)
+	}
+	if a.sensitiveDirective == "" || fc.Field.Definition == nil {
+		return ResolverArgs(fc.Field.Arguments)
+	}
+
+	args := make([]attribute.KeyValue, 0, len(fc.Field.Arguments))
+	for _, arg := range fc.Field.Arguments {
+		if arg.Value == nil {
+			continue
+		}
+		value := a.redactor.marker()
+		if argDef := fc.Field.Definition.Arguments.ForName(arg.Name); argDef == nil || !a.isSensitive(argDef.Directives) {
+			value = a.redactSensitiveValue(arg.Value).String()
+		}
+		args = append(args, attribute.String(fmt.Sprintf("%s.%s", resolverArgsPrefix, arg.Name), value))
+	}
+
+	return args
+}
+
+// redactSensitiveValue returns a copy of the value where the input fields marked
+// with the sensitive directive are replaced by the redaction marker.
+func (a Tracer) redactSensitiveValue(value *ast.Value) *ast.Value {
+	switch value.Kind {
+	case ast.ListValue, ast.ObjectValue:
+	default:
+		return value
+	}
+
+	redacted := *value
+	redacted.Children = make(ast.ChildValueList, 0, len(value.Children))
+	for _, child := range value.Children {
+		childValue := child.Value
+		if value.Kind == ast.ObjectValue && a.isSensitiveField(value.Definition, child.Name) {
+			childValue = &ast.Value{Kind: ast.EnumValue, Raw: a.redactor.marker()}
+		} else {
+			childValue = a.redactSensitiveValue(childValue)
+		}
+		redacted.Children = append(redacted.Children, &ast.ChildValue{Name: child.Name, Value: childValue})
+	}
+	return &redacted
+}
+
+// sensitiveVariablePaths returns the paths of the variables marked with the sensitive directive,
+// either on their definition, on the input fields of their type,
+// or on the arguments and input fields they are passed to.
+func (a Tracer) sensitiveVariablePaths(oc *graphql.OperationContext) []string {
+	if a.sensitiveDirective == "" || oc.Operation == nil {
+		return nil
+	}
+
+	w := sensitiveWalker{
+		tracer:    a,
+		schema:    a.schema.Load(),
+		paths:     make(map[string]struct{}),
+		fragments: make(map[string]struct{}),
+	}
+	for _, varDef := range oc.Operation.VariableDefinitions {
+		if a.isSensitive(varDef.Directives) {
+			w.paths[varDef.Variable] = struct{}{}
+			continue
+		}
+		w.walkInputType(varDef.Variable, varDef.Definition, map[string]struct{}{})
+	}
+	w.walkSelectionSet(oc.Operation.SelectionSet)
+
+	paths := make([]string, 0, len(w.paths))
+	for path := range w.paths {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// sensitiveArgPaths returns the paths of the arguments of the field marked with the sensitive directive,
+// either on their definition or on the input fields of their type.
+func (a Tracer) sensitiveArgPaths(def *ast.FieldDefinition) []string {
+	if a.sensitiveDirective == "" || def == nil {
+		return nil
+	}
+
+	w := sensitiveWalker{
+		tracer: a,
+		schema: a.schema.Load(),
+		paths:  make(map[string]struct{}),
+	}
+	for _, argDef := range def.Arguments {
+		if a.isSensitive(argDef.Directives) {
+			w.paths[argDef.Name] = struct{}{}
+			continue
+		}
+		if w.schema != nil {
+			w.walkInputType(argDef.Name, w.schema.Types[argDef.Type.Name()], map[string]struct{}{})
+		}
+	}
+
+	paths := make([]string, 0, len(w.paths))
+	for path := range w.paths {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func (a Tracer) isSensitive(directives ast.DirectiveList) bool {
+	return directives.ForName(a.sensitiveDirective) != nil
+}
+
+func (a Tracer) isSensitiveField(def *ast.Definition, name string) bool {
+	if def == nil {
+		return false
+	}
+	field := def.Fields.ForName(name)
+	return field != nil && a.isSensitive(field.Directives)
+}
+
+// sensitiveWalker collects the paths of the sensitive variables of an operation.
+type sensitiveWalker struct {
+	tracer    Tracer
+	schema    *ast.Schema
+	paths     map[string]struct{}
+	fragments map[string]struct{}
+}
+
+// walkInputType collects the sensitive input fields of the type of the value at path.
+// visiting holds the types on the way to path, to stop on recursive input types.
+func (w *sensitiveWalker) walkInputType(path string, def *ast.Definition, visiting map[string]struct{}) {
+	if def == nil || def.Kind != ast.InputObject {
+		return
+	}
+	if _, ok := visiting[def.Name]; ok {
+		return
+	}
+	visiting[def.Name] = struct{}{}
+	defer delete(visiting, def.Name)
+
+	for _, field := range def.Fields {
+		fieldPath := path + "." + field.Name
+		if w.tracer.isSensitive(field.Directives) {
+			w.paths[fieldPath] = struct{}{}
+			continue
+		}
+		if w.schema != nil {
+			w.walkInputType(fieldPath, w.schema.Types[field.Type.Name()], visiting)
+		}
+	}
+}
+
+func (w *sensitiveWalker) walkSelectionSet(selectionSet ast.SelectionSet) {
+	for _, selection := range selectionSet {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			for _, arg := range selection.Arguments {
+				sensitive := false
+				if selection.Definition != nil {
+					argDef := selection.Definition.Arguments.ForName(arg.Name)
+					sensitive = argDef != nil && w.tracer.isSensitive(argDef.Directives)
+				}
+				w.walkValue(arg.Value, sensitive)
+			}
+			w.walkSelectionSet(selection.SelectionSet)
+		case *ast.InlineFragment:
+			w.walkSelectionSet(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			if _, ok := w.fragments[selection.Name]; ok || selection.Definition == nil {
+				continue
+			}
+			w.fragments[selection.Name] = struct{}{}
+			w.walkSelectionSet(selection.Definition.SelectionSet)
+		}
+	}
+}
+
+// walkValue collects the variables used in a sensitive position of the value.
+func (w *sensitiveWalker) walkValue(value *ast.Value, sensitive bool) {
+	if value == nil {
+		return
+	}
+	switch value.Kind {
+	case ast.Variable:
+		if sensitive {
+			w.paths[value.Raw] = struct{}{}
+		}
+	case ast.ListValue:
+		for _, child := range value.Children {
+			w.walkValue(child.Value, sensitive)
+		}
+	case ast.ObjectValue:
+		for _, child := range value.Children {
+			w.walkValue(child.Value, sensitive || w.tracer.isSensitiveField(value.Definition, child.Name))
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const sensitiveSchema = `
+	directive @sensitive on ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION | VARIABLE_DEFINITION
+
+	type Query {
+		login(user: String!, password: String! @sensitive): String!
+		register(input: RegisterInput!): String!
+	}
+
+	input RegisterInput {
+		user: String!
+		password: String! @sensitive
+		profile: ProfileInput
+	}
+
+	input ProfileInput {
+		name: String!
+		phone: String @sensitive
+	}
+`
+
+func TestSensitiveResolverArgs(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: sensitiveSchema})
+	doc, err := gqlparser.LoadQuery(schema, `{
+		login(user: "john", password: "secret")
+		register(input: {user: "john", password: "secret", profile: {name: "John", phone: "555"}})
+	}`)
+	require.Nil(t, err)
+
+	tracer := Middleware(WithSensitiveDirective(""))
+	selections := doc.Operations[0].SelectionSet
+
+	loginArgs := tracer.resolverArgs(&graphql.FieldContext{Field: graphql.CollectedField{Field: selections[0].(*ast.Field)}})
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("gql.resolver.args.user", `"john"`),
+		attribute.String("gql.resolver.args.password", "[REDACTED]"),
+	}, loginArgs)
+
+	registerArgs := tracer.resolverArgs(&graphql.FieldContext{Field: graphql.CollectedField{Field: selections[1].(*ast.Field)}})
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("gql.resolver.args.input", `{user:"john",password:[REDACTED],profile:{name:"John",phone:[REDACTED]}}`),
+	}, registerArgs)
+}
+
+func TestSensitiveVariablePaths(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: sensitiveSchema})
+	doc, err := gqlparser.LoadQuery(schema, `query ($user: String!, $password: String!, $input: RegisterInput!, $token: String! @sensitive, $name: String!) {
+		login(user: $user, password: $password)
+		register(input: $input)
+		other: register(input: {user: $name, password: $token})
+	}`)
+	require.Nil(t, err)
+
+	tracer := Middleware(WithSensitiveDirective("sensitive"))
+	require.NoError(t, tracer.Validate(&graphql.ExecutableSchemaMock{SchemaFunc: func() *ast.Schema { return schema }}))
+
+	paths := tracer.sensitiveVariablePaths(&graphql.OperationContext{Operation: doc.Operations[0]})
+	assert.ElementsMatch(t, []string{"password", "input.password", "input.profile.phone", "token"}, paths)
+}
+
+func TestSensitiveDirectiveVariables(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: sensitiveSchema})
+	srv := handler.New(&graphql.ExecutableSchemaMock{
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			return graphql.OneShot(&graphql.Response{Data: []byte(`{"login":"ok"}`)})
+		},
+		SchemaFunc: func() *ast.Schema {
+			return schema
+		},
+	})
+	srv.AddTransport(&transport.POST{})
+	srv.Use(Middleware(WithTracerProvider(provider), WithSensitiveDirective("")))
+
+	body := strings.NewReader(`{"variables":{"user":"john","password":"secret"},"query":"query ($user: String!, $password: String!) { login(user: $user, password: $password) }"}`)
+	r := httptest.NewRequest("POST", "/foo", body)
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, r)
+
+	spans := endedSpans(spanRecorder)
+	require.Len(t, spans, 1)
+	user, _ := spanAttribute(spans[0], "gql.request.variables.user")
+	assert.Equal(t, "john", user.AsString())
+	password, _ := spanAttribute(spans[0], "gql.request.variables.password")
+	assert.Equal(t, "[REDACTED]", password.AsString())
+	redacted, _ := spanAttribute(spans[0], "gql.request.variables.redacted")
+	assert.Equal(t, []string{"password"}, redacted.AsStringSlice())
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestResolvedResolverArgs(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: sensitiveSchema})
+	doc, err := gqlparser.LoadQuery(schema, `query ($user: String!, $password: String!, $input: RegisterInput!) {
+		login(user: $user, password: $password)
+		register(input: $input)
+	}`)
+	require.Nil(t, err)
+
+	tracer := Middleware(WithResolvedArgs(), WithSensitiveDirective(""))
+	tracer.schema.Store(schema)
+	selections := doc.Operations[0].SelectionSet
+
+	loginArgs := tracer.resolverArgs(&graphql.FieldContext{
+		Field: graphql.CollectedField{Field: selections[0].(*ast.Field)},
+		Args:  map[string]interface{}{"user": "john", "password": "secret"},
+	})
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("gql.resolver.args.user", `"john"`),
+		attribute.String("gql.resolver.args.password", `"[REDACTED]"`),
+	}, loginArgs)
+
+	registerArgs := tracer.resolverArgs(&graphql.FieldContext{
+		Field: graphql.CollectedField{Field: selections[1].(*ast.Field)},
+		Args: map[string]interface{}{"input": map[string]interface{}{
+			"user":     "john",
+			"password": "secret",
+		}},
+	})
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("gql.resolver.args.input", `{"password":"[REDACTED]","user":"john"}`),
+	}, registerArgs)
+}
+
+// registerInput and profileInput are the models generated by gqlgen for the input objects of sensitiveSchema.
+type registerInput struct {
+	User     string        `json:"user"`
+	Password string        `json:"password"`
+	Profile  *profileInput `json:"profile,omitempty"`
+}
+
+type profileInput struct {
+	Name  string  `json:"name"`
+	Phone *string `json:"phone,omitempty"`
+}
+
+func TestResolvedResolverArgsTypedValues(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: sensitiveSchema})
+	doc, err := gqlparser.LoadQuery(schema, `query ($user: String!, $input: RegisterInput!) {
+		login(user: $user, password: "secret")
+		register(input: $input)
+	}`)
+	require.Nil(t, err)
+
+	tracer := Middleware(WithResolvedArgs(), WithSensitiveDirective(""))
+	tracer.schema.Store(schema)
+	selections := doc.Operations[0].SelectionSet
+
+	user := "john"
+	loginArgs := tracer.resolverArgs(&graphql.FieldContext{
+		Field: graphql.CollectedField{Field: selections[0].(*ast.Field)},
+		Args:  map[string]interface{}{"user": &user, "password": "secret"},
+	})
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("gql.resolver.args.user", `"john"`),
+		attribute.String("gql.resolver.args.password", `"[REDACTED]"`),
+	}, loginArgs)
+
+	phone := "555-0100"
+	registerArgs := tracer.resolverArgs(&graphql.FieldContext{
+		Field: graphql.CollectedField{Field: selections[1].(*ast.Field)},
+		Args: map[string]interface{}{"input": registerInput{
+			User:     "john",
+			Password: "secret",
+			Profile:  &profileInput{Name: "John", Phone: &phone},
+		}},
+	})
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("gql.resolver.args.input",
+			`{"password":"[REDACTED]","profile":{"name":"John","phone":"[REDACTED]"},"user":"john"}`),
+	}, registerArgs)
+}
+
+// untaggedRegisterInput and untaggedProfileInput are autobound models of the input objects of sensitiveSchema,
+// without json tags.
+type untaggedRegisterInput struct {
+	User     string
+	Password string
+	Profile  *untaggedProfileInput
+}
+
+type untaggedProfileInput struct {
+	Name  string
+	Phone *string
+}
+
+func TestResolvedResolverArgsUntaggedStruct(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: sensitiveSchema})
+	doc, err := gqlparser.LoadQuery(schema, `query ($input: RegisterInput!) {
+		register(input: $input)
+	}`)
+	require.Nil(t, err)
+
+	tracer := Middleware(WithResolvedArgs(), WithSensitiveDirective(""))
+	tracer.schema.Store(schema)
+	field := doc.Operations[0].SelectionSet[0].(*ast.Field)
+
+	phone := "555-0100"
+	registerArgs := tracer.resolverArgs(&graphql.FieldContext{
+		Field: graphql.CollectedField{Field: field},
+		Args: map[string]interface{}{"input": &untaggedRegisterInput{
+			User:     "bob",
+			Password: "hunter2",
+			Profile:  &untaggedProfileInput{Name: "Bob", Phone: &phone},
+		}},
+	})
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("gql.resolver.args.input",
+			`{"password":"[REDACTED]","profile":{"name":"Bob","phone":"[REDACTED]"},"user":"bob"}`),
+	}, registerArgs)
+
+	// an arg whose input fields cannot be resolved is redacted as a whole
+	registerArgs = tracer.resolverArgs(&graphql.FieldContext{
+		Field: graphql.CollectedField{Field: field},
+		Args:  map[string]interface{}{"input": json.RawMessage(`{"User":"bob","Password":"hunter2"}`)},
+	})
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("gql.resolver.args.input", `"[REDACTED]"`),
+	}, registerArgs)
+}
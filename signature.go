@@ -0,0 +1,160 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// querySignature returns the normalized signature of the operation, along with the fragments it uses:
+// literals are stripped, aliases are dropped, selections and arguments are sorted,
+// and whitespace is collapsed, so that operations differing only by these share a signature.
+func querySignature(doc *ast.QueryDocument, op *ast.OperationDefinition) string {
+	p := signaturePrinter{doc: doc, fragments: make(map[string]string)}
+
+	var b strings.Builder
+	b.WriteString(string(op.Operation))
+	if op.Name != "" {
+		b.WriteString(" " + op.Name)
+	}
+	if len(op.VariableDefinitions) > 0 {
+		vars := make([]string, 0, len(op.VariableDefinitions))
+		for _, v := range op.VariableDefinitions {
+			vars = append(vars, "$"+v.Variable+":"+v.Type.String()+p.directives(v.Directives))
+		}
+		sort.Strings(vars)
+		b.WriteString("(" + strings.Join(vars, ",") + ")")
+	}
+	b.WriteString(p.directives(op.Directives))
+	b.WriteString(p.selectionSet(op.SelectionSet))
+
+	// the fragments are collected while printing the selections, including the nested ones
+	names := make([]string, 0, len(p.fragments))
+	for name := range p.fragments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString(" " + p.fragments[name])
+	}
+
+	return b.String()
+}
+
+// querySignatureHash returns the hex-encoded SHA-256 hash of the signature.
+func querySignatureHash(signature string) string {
+	sum := sha256.Sum256([]byte(signature))
+	return hex.EncodeToString(sum[:])
+}
+
+type signaturePrinter struct {
+	doc *ast.QueryDocument
+	// fragments holds the printed fragments used by the operation, by name
+	fragments map[string]string
+}
+
+func (p *signaturePrinter) selectionSet(selectionSet ast.SelectionSet) string {
+	if len(selectionSet) == 0 {
+		return ""
+	}
+	selections := make([]string, 0, len(selectionSet))
+	for _, selection := range selectionSet {
+		selections = append(selections, p.selection(selection))
+	}
+	sort.Strings(selections)
+	return "{" + strings.Join(selections, " ") + "}"
+}
+
+func (p *signaturePrinter) selection(selection ast.Selection) string {
+	switch selection := selection.(type) {
+	case *ast.Field:
+		return selection.Name + p.arguments(selection.Arguments) + p.directives(selection.Directives) +
+			p.selectionSet(selection.SelectionSet)
+	case *ast.InlineFragment:
+		s := "..."
+		if selection.TypeCondition != "" {
+			s += "on " + selection.TypeCondition
+		}
+		return s + p.directives(selection.Directives) + p.selectionSet(selection.SelectionSet)
+	case *ast.FragmentSpread:
+		p.collectFragment(selection.Name)
+		return "..." + selection.Name + p.directives(selection.Directives)
+	default:
+		return ""
+	}
+}
+
+// collectFragment prints the fragment once, along with the fragments it uses.
+func (p *signaturePrinter) collectFragment(name string) {
+	if _, ok := p.fragments[name]; ok || p.doc == nil {
+		return
+	}
+	f := p.doc.Fragments.ForName(name)
+	if f == nil {
+		return
+	}
+	// set before printing the selections, to stop on recursive fragments
+	p.fragments[name] = ""
+	p.fragments[name] = "fragment " + f.Name + " on " + f.TypeCondition + p.directives(f.Directives) +
+		p.selectionSet(f.SelectionSet)
+}
+
+func (p *signaturePrinter) arguments(args ast.ArgumentList) string {
+	if len(args) == 0 {
+		return ""
+	}
+	printed := make([]string, 0, len(args))
+	for _, arg := range args {
+		printed = append(printed, arg.Name+":"+signatureValue(arg.Value))
+	}
+	sort.Strings(printed)
+	return "(" + strings.Join(printed, ",") + ")"
+}
+
+func (p *signaturePrinter) directives(directives ast.DirectiveList) string {
+	var s string
+	for _, d := range directives {
+		s += "@" + d.Name + p.arguments(d.Arguments)
+	}
+	return s
+}
+
+// signatureValue returns the value with the literals stripped:
+// numbers become 0, strings "", lists [] and objects {}.
+func signatureValue(value *ast.Value) string {
+	if value == nil {
+		return ""
+	}
+	switch value.Kind {
+	case ast.Variable:
+		return "$" + value.Raw
+	case ast.IntValue, ast.FloatValue:
+		return "0"
+	case ast.StringValue, ast.BlockValue:
+		return `""`
+	case ast.ListValue:
+		return "[]"
+	case ast.ObjectValue:
+		return "{}"
+	default:
+		// booleans, enums and null are kept, as they usually select a different behavior
+		return value.Raw
+	}
+}
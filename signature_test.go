@@ -0,0 +1,132 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQuerySignature(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{
+			name:     "shorthand",
+			query:    `{ name }`,
+			expected: `query{name}`,
+		},
+		{
+			name:     "literals",
+			query:    `query Q { find(id: 42, name: "john", tags: ["a"], filter: {x: 1.5}, active: true, kind: USER, other: null) }`,
+			expected: `query Q{find(active:true,filter:{},id:0,kind:USER,name:"",other:null,tags:[])}`,
+		},
+		{
+			name: "aliases, order and whitespace",
+			query: `query Q($b: String, $a: Int!) {
+				second: b(x: $b)
+				a   @include(if: $a) { z y }
+			}`,
+			expected: `query Q($a:Int!,$b:String){a@include(if:$a){y z} b(x:$b)}`,
+		},
+		{
+			name: "fragments",
+			query: `query Q { ...F ... on User { id } }
+				fragment F on Query { user { ...G } }
+				fragment G on User { name }
+				fragment Unused on User { id }`,
+			expected: `query Q{...F ...on User{id}} fragment F on Query{user{...G}} fragment G on User{name}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.ParseQuery(&ast.Source{Input: tt.query})
+			require.Nil(t, err)
+			assert.Equal(t, tt.expected, querySignature(doc, doc.Operations[0]))
+		})
+	}
+}
+
+func TestQuerySignatureHash(t *testing.T) {
+	parse := func(query string) string {
+		doc, err := parser.ParseQuery(&ast.Source{Input: query})
+		require.Nil(t, err)
+		return querySignatureHash(querySignature(doc, doc.Operations[0]))
+	}
+
+	hash := parse(`query Q { find(id: 1) }`)
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, parse(`query Q {
+		result: find(id: 2)
+	}`))
+	assert.NotEqual(t, hash, parse(`query Other { find(id: 1) }`))
+}
+
+func TestWithQuerySignature(t *testing.T) {
+	tests := []struct {
+		name          string
+		option        Option
+		wantQuery     bool
+		wantSignature bool
+	}{
+		{name: "signature", option: WithQuerySignature(), wantQuery: true, wantSignature: true},
+		{name: "hash only", option: WithQueryHashOnly()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spanRecorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+			srv := newMockServer(func(_ context.Context) (interface{}, error) {
+				return &graphql.Response{Data: []byte(`{"name":"test"}`)}, nil
+			})
+			srv.Use(Middleware(WithTracerProvider(provider), tt.option))
+
+			r := httptest.NewRequest("GET", "/foo?query={find(id:1)}", nil)
+			w := httptest.NewRecorder()
+
+			srv.ServeHTTP(w, r)
+
+			spans := endedSpans(spanRecorder)
+			require.Len(t, spans, 2)
+			_, ok := spanAttribute(spans[1], requestQueryKey)
+			assert.Equal(t, tt.wantQuery, ok)
+			signature, ok := spanAttribute(spans[1], requestSignatureKey)
+			assert.Equal(t, tt.wantSignature, ok)
+			if ok {
+				assert.Equal(t, "query{find(id:0)}", signature.AsString())
+			}
+			hash, ok := spanAttribute(spans[1], requestSignatureHashKey)
+			assert.True(t, ok)
+			assert.Equal(t, querySignatureHash("query{find(id:0)}"), hash.AsString())
+
+			assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		})
+	}
+}
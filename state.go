@@ -0,0 +1,104 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+type operationStateCtxKey struct{}
+
+// operationState holds what the field spans of an operation already recorded,
+// so that it is not recorded again on the operation span.
+// The fields are resolved concurrently, so it is guarded by a mutex.
+type operationState struct {
+	mu sync.Mutex
+	// panics holds the fields whose panic was recorded on their span.
+	panics map[*graphql.FieldContext]struct{}
+	// errors holds the keys of the errors recorded on a field span.
+	errors map[string]struct{}
+}
+
+func withOperationState(ctx context.Context) context.Context {
+	return context.WithValue(ctx, operationStateCtxKey{}, &operationState{
+		panics: make(map[*graphql.FieldContext]struct{}),
+		errors: make(map[string]struct{}),
+	})
+}
+
+func getOperationState(ctx context.Context) *operationState {
+	state, _ := ctx.Value(operationStateCtxKey{}).(*operationState)
+	return state
+}
+
+// addRecordedPanic registers the panic of the field, if the context holds an operation state.
+func addRecordedPanic(ctx context.Context, fc *graphql.FieldContext) {
+	if state := getOperationState(ctx); state != nil {
+		state.mu.Lock()
+		state.panics[fc] = struct{}{}
+		state.mu.Unlock()
+	}
+}
+
+// takeRecordedPanic reports whether the panic of the field was recorded, and unregisters it.
+func takeRecordedPanic(ctx context.Context, fc *graphql.FieldContext) bool {
+	state := getOperationState(ctx)
+	if state == nil || fc == nil {
+		return false
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	_, ok := state.panics[fc]
+	delete(state.panics, fc)
+	return ok
+}
+
+// addAttributedErrors registers the errors recorded on a field span, if the context holds an operation state.
+func addAttributedErrors(ctx context.Context, errList gqlerror.List) {
+	if state := getOperationState(ctx); state != nil {
+		state.mu.Lock()
+		for _, err := range errList {
+			state.errors[errorKey(err)] = struct{}{}
+		}
+		state.mu.Unlock()
+	}
+}
+
+// unattributedErrors returns the errors not recorded on a field span.
+func unattributedErrors(ctx context.Context, errList gqlerror.List) gqlerror.List {
+	state := getOperationState(ctx)
+	if state == nil {
+		return errList
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	var unattributed gqlerror.List
+	for _, err := range errList {
+		if _, ok := state.errors[errorKey(err)]; !ok {
+			unattributed = append(unattributed, err)
+		}
+	}
+	return unattributed
+}
+
+// errorKey identifies an error by its path: the response errors are copies of the errors found on the fields,
+// whose message may be changed by the error presenter, and the errors at the path of a field are all recorded on its span.
+func errorKey(err *gqlerror.Error) string {
+	return err.Path.String()
+}
@@ -0,0 +1,428 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestSubscriptionSpans(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockSubscriptionServer([]*graphql.Response{
+		{Data: []byte(`{"name":"a"}`)},
+		{Data: []byte(`{"name":"bb"}`), Errors: gqlerror.List{gqlerror.Errorf("event error")}},
+		{Data: []byte(`{"name":"ccc"}`)},
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithSubscriptionSpans()))
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, newSubscriptionRequest())
+
+	spans := endedSpans(spanRecorder)
+	require.Len(t, spans, 1)
+	subscriptionSpan := spans[0]
+	assert.Equal(t, "OnName", subscriptionSpan.Name())
+	assert.Equal(t, codes.Error, subscriptionSpan.Status().Code)
+	eventCount, _ := spanAttribute(subscriptionSpan, subscriptionEventCountKey)
+	assert.Equal(t, int64(3), eventCount.AsInt64())
+
+	// the errors of an event are recorded as exception events following it
+	events := subscriptionSpan.Events()
+	require.Len(t, events, 4)
+	assert.Equal(t, semconv.ExceptionEventName, events[2].Name)
+	assert.Contains(t, events[2].Attributes, semconv.ExceptionMessage("event error"))
+	events = append(events[:2], events[3])
+	for i, event := range events {
+		assert.Equal(t, subscriptionEventName, event.Name)
+		assert.Contains(t, event.Attributes, SubscriptionSequence(int64(i+1)))
+		assert.Contains(t, event.Attributes, SubscriptionPayloadSize(int64(len(`{"name":""}`)+i+1)))
+	}
+	assert.Contains(t, events[1].Attributes, resolverErrorCountKey.Int64(1))
+	assert.Contains(t, events[1].Attributes, ResponseOutcome(ResponseOutcomePartial))
+
+	// the phases are recorded once for the whole subscription
+	assert.Len(t, spanRecorder.Ended(), 4)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestSubscriptionSpansErrorHandling(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantCode   codes.Code
+		wantEvents int
+	}{
+		{
+			name:       "failure",
+			wantCode:   codes.Error,
+			wantEvents: 4,
+		},
+		{
+			name:       "recorded",
+			opts:       []Option{WithErrorClassifier(ClassifyErrorCodes(ErrorClassRecorded, "NOT_FOUND"))},
+			wantCode:   codes.Ok,
+			wantEvents: 4,
+		},
+		{
+			name:       "ignored",
+			opts:       []Option{WithErrorClassifier(ClassifyErrorCodes(ErrorClassIgnored, "NOT_FOUND"))},
+			wantCode:   codes.Ok,
+			wantEvents: 3,
+		},
+		{
+			name:       "partial success policy",
+			opts:       []Option{WithStatusPolicy(PartialSuccessStatusPolicy(codes.Unset))},
+			wantCode:   codes.Unset,
+			wantEvents: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spanRecorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+			notFound := gqlerror.Errorf("not found")
+			notFound.Extensions = map[string]interface{}{"code": "NOT_FOUND"}
+			srv := newMockSubscriptionServer([]*graphql.Response{
+				{Data: []byte(`{"name":"a"}`)},
+				{Data: []byte(`{"name":"b"}`), Errors: gqlerror.List{notFound}},
+				{Data: []byte(`{"name":"c"}`)},
+			})
+			srv.Use(Middleware(append([]Option{WithTracerProvider(provider), WithSubscriptionSpans()}, tt.opts...)...))
+
+			srv.ServeHTTP(httptest.NewRecorder(), newSubscriptionRequest())
+
+			spans := endedSpans(spanRecorder)
+			require.Len(t, spans, 1)
+			assert.Equal(t, tt.wantCode, spans[0].Status().Code)
+			assert.Len(t, spans[0].Events(), tt.wantEvents)
+		})
+	}
+}
+
+func TestSubscriptionSpansFieldErrorAttribution(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockFieldSubscriptionServer(2, func(_ context.Context) (interface{}, error) {
+		return nil, fmt.Errorf("event error")
+	})
+	srv.Use(Middleware(WithTracerProvider(provider), WithSubscriptionSpans(), WithFieldErrorAttribution()))
+
+	srv.ServeHTTP(httptest.NewRecorder(), newSubscriptionRequest())
+
+	spans := endedSpans(spanRecorder)
+	require.Len(t, spans, 3)
+	for _, fieldSpan := range spans[:2] {
+		require.Len(t, fieldSpan.Events(), 1)
+		assert.Equal(t, semconv.ExceptionEventName, fieldSpan.Events()[0].Name)
+	}
+	// the errors recorded on the field spans are not recorded again on the subscription span
+	subscriptionSpan := spans[2]
+	assert.Equal(t, codes.Error, subscriptionSpan.Status().Code)
+	for _, event := range subscriptionSpan.Events() {
+		assert.Equal(t, subscriptionEventName, event.Name)
+	}
+}
+
+func TestSubscriptionWithoutSubscriptionSpans(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	srv := newMockSubscriptionServer([]*graphql.Response{
+		{Data: []byte(`{"name":"a"}`)},
+		{Data: []byte(`{"name":"b"}`)},
+	})
+	srv.Use(Middleware(WithTracerProvider(provider)))
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, newSubscriptionRequest())
+
+	// a disconnected operation span for each event, and one for the end of the stream
+	spans := spanRecorder.Ended()
+	require.Len(t, spans, 3)
+	for _, s := range spans {
+		assert.Equal(t, "OnName", s.Name())
+		assert.Empty(t, s.Events())
+	}
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestSubscriptionSpansParentFieldSpans(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+	tracer := Middleware(WithTracerProvider(provider), WithSubscriptionSpans())
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Query {
+			name: String!
+		}
+		type Subscription {
+			name: String!
+		}
+	`})
+	oc := &graphql.OperationContext{
+		RawQuery:  "subscription OnName { name }",
+		Operation: &ast.OperationDefinition{Operation: ast.Subscription, Name: "OnName"},
+	}
+	events := 0
+	handler := tracer.InterceptOperation(graphql.WithOperationContext(context.Background(), oc),
+		func(_ context.Context) graphql.ResponseHandler {
+			return func(ctx context.Context) *graphql.Response {
+				if events == 2 {
+					return nil
+				}
+				events++
+				ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{
+					Object: "Subscription",
+					Field: graphql.CollectedField{
+						Field: &ast.Field{
+							Name:             "name",
+							Definition:       schema.Types["Subscription"].Fields.ForName("name"),
+							ObjectDefinition: schema.Types["Subscription"],
+						},
+					},
+				})
+				_, err := tracer.InterceptField(ctx, func(_ context.Context) (interface{}, error) {
+					return "name", nil
+				})
+				require.NoError(t, err)
+				return &graphql.Response{Data: []byte(`{"name":"name"}`)}
+			}
+		})
+	// the events are resolved with a context which is not derived from the one of the operation
+	eventCtx := graphql.WithOperationContext(context.Background(), oc)
+	eventCtx = graphql.WithResponseContext(eventCtx, graphql.DefaultErrorPresenter, graphql.DefaultRecover)
+	for resp := handler(eventCtx); resp != nil; resp = handler(eventCtx) {
+		assert.Empty(t, resp.Errors)
+	}
+
+	spans := endedSpans(spanRecorder)
+	require.Len(t, spans, 3)
+	subscriptionSpan := spans[2]
+	assert.Equal(t, "OnName", subscriptionSpan.Name())
+	for _, fieldSpan := range spans[:2] {
+		assert.Equal(t, "Subscription/name", fieldSpan.Name())
+		assert.Equal(t, subscriptionSpan.SpanContext().SpanID(), fieldSpan.Parent().SpanID())
+	}
+}
+
+func TestSubscriptionMetrics(t *testing.T) {
+	for _, subscriptionSpans := range []bool{false, true} {
+		t.Run(fmt.Sprintf("subscriptionSpans=%t", subscriptionSpans), func(t *testing.T) {
+			reader := sdkmetric.NewManualReader()
+			meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+			opts := []Option{
+				WithTracerProvider(sdktrace.NewTracerProvider()),
+				WithMeterProvider(meterProvider),
+			}
+			if subscriptionSpans {
+				opts = append(opts, WithSubscriptionSpans())
+			}
+
+			srv := newMockSubscriptionServer([]*graphql.Response{
+				{Data: []byte(`{"name":"a"}`)},
+				{Data: []byte(`{"name":"b"}`), Errors: gqlerror.List{gqlerror.Errorf("event error")}},
+				{Data: []byte(`{"name":"c"}`)},
+			})
+			srv.Use(Middleware(opts...))
+
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, newSubscriptionRequest())
+			assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			rm := collectMetrics(t, reader)
+			count, ok := findMetric(rm, requestCountMetric).Data.(metricdata.Sum[int64])
+			require.True(t, ok)
+			require.Len(t, count.DataPoints, 1)
+			expectedAttrs := attribute.NewSet(
+				RequestOperationName("OnName"),
+				RequestOperationType("subscription"),
+				ResponseHasError(true),
+				ResponseOutcome(ResponseOutcomePartial),
+			)
+			assert.Equal(t, int64(1), count.DataPoints[0].Value)
+			assert.Equal(t, expectedAttrs, count.DataPoints[0].Attributes)
+
+			duration, ok := findMetric(rm, operationDurationMetric).Data.(metricdata.Histogram[float64])
+			require.True(t, ok)
+			require.Len(t, duration.DataPoints, 1)
+			assert.Equal(t, uint64(1), duration.DataPoints[0].Count)
+			assert.Equal(t, expectedAttrs, duration.DataPoints[0].Attributes)
+		})
+	}
+}
+
+func TestRejectedSubscription(t *testing.T) {
+	for _, subscriptionSpans := range []bool{false, true} {
+		t.Run(fmt.Sprintf("subscriptionSpans=%t", subscriptionSpans), func(t *testing.T) {
+			spanRecorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+			reader := sdkmetric.NewManualReader()
+			meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+			opts := []Option{WithTracerProvider(provider), WithMeterProvider(meterProvider)}
+			if subscriptionSpans {
+				opts = append(opts, WithSubscriptionSpans())
+			}
+
+			srv := newMockSubscriptionServer([]*graphql.Response{{Data: []byte(`{"name":"a"}`)}})
+			// the mock schema fields have no complexity, so any operation exceeds a negative limit
+			srv.Use(extension.FixedComplexityLimit(-1))
+			srv.Use(Middleware(opts...))
+
+			srv.ServeHTTP(httptest.NewRecorder(), newSubscriptionRequest())
+
+			spans := endedSpans(spanRecorder)
+			require.Len(t, spans, 1)
+			assert.Equal(t, "OnName", spans[0].Name())
+			assert.Equal(t, codes.Error, spans[0].Status().Code)
+			phase, _ := spanAttribute(spans[0], requestRejectionPhaseKey)
+			assert.Equal(t, RejectionPhaseComplexity, phase.AsString())
+
+			rm := collectMetrics(t, reader)
+			count, ok := findMetric(rm, requestCountMetric).Data.(metricdata.Sum[int64])
+			require.True(t, ok)
+			require.Len(t, count.DataPoints, 1)
+			assert.Equal(t, int64(1), count.DataPoints[0].Value)
+			duration, ok := findMetric(rm, operationDurationMetric).Data.(metricdata.Histogram[float64])
+			require.True(t, ok)
+			require.Len(t, duration.DataPoints, 1)
+			assert.Equal(t, uint64(1), duration.DataPoints[0].Count)
+			complexity, ok := findMetric(rm, complexityMetric).Data.(metricdata.Histogram[int64])
+			require.True(t, ok)
+			require.Len(t, complexity.DataPoints, 1)
+		})
+	}
+}
+
+func newSubscriptionRequest() *http.Request {
+	body := strings.NewReader(`{"query":"subscription OnName { name }"}`)
+	r := httptest.NewRequest("POST", "/foo", body)
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "text/event-stream")
+	return r
+}
+
+// newMockSubscriptionServer provides a server streaming the given responses to subscriptions over SSE.
+func newMockSubscriptionServer(responses []*graphql.Response) *handler.Server {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Query {
+			name: String!
+		}
+		type Subscription {
+			name: String!
+		}
+	`})
+	srv := handler.New(&graphql.ExecutableSchemaMock{
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			rc := graphql.GetOperationContext(ctx)
+			switch rc.Operation.Operation {
+			case ast.Subscription:
+				next := 0
+				return func(_ context.Context) *graphql.Response {
+					if next == len(responses) {
+						return nil
+					}
+					next++
+					return responses[next-1]
+				}
+			default:
+				return graphql.OneShot(graphql.ErrorResponse(ctx, "unsupported GraphQL operation"))
+			}
+		},
+		SchemaFunc: func() *ast.Schema {
+			return schema
+		},
+		ComplexityFunc: func(_ context.Context, _ string, _ string, childComplexity int, _ map[string]any) (int, bool) {
+			return childComplexity, true
+		},
+	})
+	srv.AddTransport(transport.SSE{})
+
+	return srv
+}
+
+// newMockFieldSubscriptionServer provides a server streaming the given number of events to subscriptions over SSE,
+// each of them resolving the name field with the resolver like the generated code does.
+func newMockFieldSubscriptionServer(events int, resolver func(ctx context.Context) (interface{}, error)) *handler.Server {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Query {
+			name: String!
+		}
+		type Subscription {
+			name: String!
+		}
+	`})
+	srv := handler.New(&graphql.ExecutableSchemaMock{
+		ExecFunc: func(_ context.Context) graphql.ResponseHandler {
+			sent := 0
+			return func(ctx context.Context) *graphql.Response {
+				if sent == events {
+					return nil
+				}
+				sent++
+				ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{
+					Object: "Subscription",
+					Field: graphql.CollectedField{
+						Field: &ast.Field{
+							Name:             "name",
+							Definition:       schema.Types["Subscription"].Fields.ForName("name"),
+							ObjectDefinition: schema.Types["Subscription"],
+						},
+					},
+				})
+				res, err := graphql.GetOperationContext(ctx).ResolverMiddleware(ctx, resolver)
+				if err != nil {
+					graphql.AddError(ctx, err)
+					return &graphql.Response{Data: []byte(`null`)}
+				}
+				return res.(*graphql.Response)
+			}
+		},
+		SchemaFunc: func() *ast.Schema {
+			return schema
+		},
+	})
+	srv.AddTransport(transport.SSE{})
+
+	return srv
+}
@@ -15,12 +15,15 @@
 package otelgqlgen
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/vektah/gqlparser/v2/ast"
 	"github.com/vektah/gqlparser/v2/gqlerror"
 
 	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 const (
@@ -28,6 +31,14 @@ const (
 	resolverArgsPrefix            = "gql.resolver.args"
 	resolverErrorPrefix           = "gql.resolver.error"
 	requestQueryKey               = attribute.Key("gql.request.query")
+	requestQueryLengthKey         = attribute.Key("gql.request.queryLength")
+	persistedQueryHashKey         = attribute.Key("gql.request.persistedQuery.hash")
+	persistedQueryOutcomeKey      = attribute.Key("gql.request.persistedQuery.outcome")
+	requestRejectionPhaseKey      = attribute.Key("gql.request.rejectionPhase")
+	requestSignatureKey           = attribute.Key("gql.request.signature")
+	requestSignatureHashKey       = attribute.Key("gql.request.signatureHash")
+	requestVariablesRedactedKey   = attribute.Key("gql.request.variables.redacted")
+	requestVariablesTruncatedKey  = attribute.Key("gql.request.variables.truncated")
 	requestComplexityLimitKey     = attribute.Key("gql.request.complexityLimit")
 	requestOperationComplexityKey = attribute.Key("gql.request.operationComplexity")
 	resolverPathKey               = attribute.Key("gql.resolver.path")
@@ -36,6 +47,15 @@ const (
 	resolverAliasKey              = attribute.Key("gql.resolver.alias")
 	resolverHasErrorKey           = attribute.Key("gql.resolver.hasError")
 	resolverErrorCountKey         = attribute.Key("gql.resolver.errorCount")
+	responseHasErrorKey           = attribute.Key("gql.response.hasError")
+	responseOutcomeKey            = attribute.Key("gql.response.outcome")
+	errorPathKey                  = attribute.Key("gql.error.path")
+	errorLocationsKey             = attribute.Key("gql.error.locations")
+	errorCodeKey                  = attribute.Key("gql.error.code")
+	errorRuleKey                  = attribute.Key("gql.error.rule")
+	subscriptionSequenceKey       = attribute.Key("gql.subscription.sequence")
+	subscriptionPayloadSizeKey    = attribute.Key("gql.subscription.payloadSize")
+	subscriptionEventCountKey     = attribute.Key("gql.subscription.eventCount")
 )
 
 // RequestQuery sets the request query.
@@ -43,6 +63,43 @@ func RequestQuery(requestQuery string) attribute.KeyValue {
 	return requestQueryKey.String(requestQuery)
 }
 
+// RequestQueryLength sets the length of the request query in bytes.
+func RequestQueryLength(length int) attribute.KeyValue {
+	return requestQueryLengthKey.Int(length)
+}
+
+// PersistedQueryHash sets the hash of the automatic persisted query.
+func PersistedQueryHash(hash string) attribute.KeyValue {
+	return persistedQueryHashKey.String(hash)
+}
+
+// PersistedQueryOutcome sets the outcome of the automatic persisted query:
+// PersistedQueryHit, PersistedQueryMiss or PersistedQueryRegister.
+func PersistedQueryOutcome(outcome string) attribute.KeyValue {
+	return persistedQueryOutcomeKey.String(outcome)
+}
+
+// RequestRejectionPhase sets the phase that rejected the request before its execution,
+// e.g. RejectionPhaseParse.
+func RequestRejectionPhase(phase string) attribute.KeyValue {
+	return requestRejectionPhaseKey.String(phase)
+}
+
+// RequestSignature sets the normalized signature of the request query.
+func RequestSignature(signature string) attribute.KeyValue {
+	return requestSignatureKey.String(signature)
+}
+
+// RequestSignatureHash sets the hash of the normalized signature of the request query.
+func RequestSignatureHash(hash string) attribute.KeyValue {
+	return requestSignatureHashKey.String(hash)
+}
+
+// RequestDocument sets the request document following the semantic conventions.
+func RequestDocument(document string) attribute.KeyValue {
+	return semconv.GraphqlDocument(document)
+}
+
 // RequestComplexityLimit sets the complexity limit.
 func RequestComplexityLimit(complexityLimit int64) attribute.KeyValue {
 	return requestComplexityLimitKey.Int64(complexityLimit)
@@ -53,6 +110,41 @@ func RequestOperationComplexity(complexityLimit int64) attribute.KeyValue {
 	return requestOperationComplexityKey.Int64(complexityLimit)
 }
 
+// RequestOperationName sets the operation name.
+func RequestOperationName(operationName string) attribute.KeyValue {
+	return semconv.GraphqlOperationName(operationName)
+}
+
+// RequestOperationType sets the operation type.
+func RequestOperationType(operationType string) attribute.KeyValue {
+	return semconv.GraphqlOperationTypeKey.String(operationType)
+}
+
+// ResponseHasError sets whether the response contains errors.
+func ResponseHasError(hasError bool) attribute.KeyValue {
+	return responseHasErrorKey.Bool(hasError)
+}
+
+// ResponseOutcome sets the outcome of the response: success, partial or failure.
+func ResponseOutcome(outcome string) attribute.KeyValue {
+	return responseOutcomeKey.String(outcome)
+}
+
+// SubscriptionSequence sets the sequence number of a subscription event.
+func SubscriptionSequence(sequence int64) attribute.KeyValue {
+	return subscriptionSequenceKey.Int64(sequence)
+}
+
+// SubscriptionPayloadSize sets the payload size, in bytes, of a subscription event.
+func SubscriptionPayloadSize(size int64) attribute.KeyValue {
+	return subscriptionPayloadSizeKey.Int64(size)
+}
+
+// SubscriptionEventCount sets the number of events sent by a subscription.
+func SubscriptionEventCount(count int64) attribute.KeyValue {
+	return subscriptionEventCountKey.Int64(count)
+}
+
 // RequestVariables sets request variables.
 func RequestVariables(requestVariables map[string]interface{}) []attribute.KeyValue {
 	variables := make([]attribute.KeyValue, 0, len(requestVariables))
@@ -64,6 +156,156 @@ func RequestVariables(requestVariables map[string]interface{}) []attribute.KeyVa
 	return variables
 }
 
+// JSONRequestVariables sets request variables, encoding each of them as JSON.
+func JSONRequestVariables(requestVariables map[string]interface{}) []attribute.KeyValue {
+	variables := make([]attribute.KeyValue, 0, len(requestVariables))
+	for k, v := range requestVariables {
+		variables = append(variables,
+			attribute.String(fmt.Sprintf("%s.%s", requestVariablesPrefix, k), jsonString(v)),
+		)
+	}
+	return variables
+}
+
+// TypedRequestVariables returns a RequestVariablesBuilderFunc setting request variables as typed
+// attributes: numbers, booleans, strings and homogeneous lists of them keep their type.
+// Input objects are flattened into dotted keys up to maxDepth levels, deeper values and
+// heterogeneous lists being encoded as JSON. At most maxAttributes variables attributes are set,
+// gql.request.variables.truncated being set when some are dropped.
+// A zero or negative limit means no limit.
+func TypedRequestVariables(maxDepth, maxAttributes int) RequestVariablesBuilderFunc {
+	return func(requestVariables map[string]interface{}) []attribute.KeyValue {
+		b := typedVariablesBuilder{maxDepth: maxDepth, maxAttributes: maxAttributes}
+		b.add(requestVariablesPrefix, requestVariables, 0)
+		if b.truncated {
+			b.variables = append(b.variables, requestVariablesTruncatedKey.Bool(true))
+		}
+		return b.variables
+	}
+}
+
+type typedVariablesBuilder struct {
+	maxDepth      int
+	maxAttributes int
+	variables     []attribute.KeyValue
+	truncated     bool
+}
+
+func (b *typedVariablesBuilder) add(prefix string, object map[string]interface{}, depth int) {
+	// sorted keys keep the truncation stable between requests
+	keys := make([]string, 0, len(object))
+	for k := range object {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := prefix + "." + k
+		if nested, ok := object[k].(map[string]interface{}); ok && (b.maxDepth <= 0 || depth+1 < b.maxDepth) {
+			b.add(key, nested, depth+1)
+			continue
+		}
+		if b.maxAttributes > 0 && len(b.variables) >= b.maxAttributes {
+			b.truncated = true
+			return
+		}
+		b.variables = append(b.variables, attribute.KeyValue{Key: attribute.Key(key), Value: typedValue(object[k])})
+	}
+}
+
+// typedValue converts a variable value to an attribute value, falling back to JSON.
+func typedValue(v interface{}) attribute.Value {
+	switch v := v.(type) {
+	case string:
+		return attribute.StringValue(v)
+	case bool:
+		return attribute.BoolValue(v)
+	case int:
+		return attribute.IntValue(v)
+	case int32:
+		return attribute.Int64Value(int64(v))
+	case int64:
+		return attribute.Int64Value(v)
+	case float32:
+		return attribute.Float64Value(float64(v))
+	case float64:
+		return attribute.Float64Value(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return attribute.Int64Value(i)
+		}
+		if f, err := v.Float64(); err == nil {
+			return attribute.Float64Value(f)
+		}
+		return attribute.StringValue(v.String())
+	case []interface{}:
+		if value, ok := typedSlice(v); ok {
+			return value
+		}
+	}
+	return attribute.StringValue(jsonString(v))
+}
+
+// typedSlice converts a homogeneous list of scalars to an attribute value.
+func typedSlice(list []interface{}) (attribute.Value, bool) {
+	if len(list) == 0 {
+		return attribute.Value{}, false
+	}
+	values := make([]attribute.Value, len(list))
+	for i, item := range list {
+		switch item.(type) {
+		case []interface{}, map[string]interface{}:
+			return attribute.Value{}, false
+		}
+		values[i] = typedValue(item)
+		if values[i].Type() != values[0].Type() {
+			return attribute.Value{}, false
+		}
+	}
+
+	switch values[0].Type() {
+	case attribute.STRING:
+		slice := make([]string, len(values))
+		for i, value := range values {
+			slice[i] = value.AsString()
+		}
+		return attribute.StringSliceValue(slice), true
+	case attribute.BOOL:
+		slice := make([]bool, len(values))
+		for i, value := range values {
+			slice[i] = value.AsBool()
+		}
+		return attribute.BoolSliceValue(slice), true
+	case attribute.INT64:
+		slice := make([]int64, len(values))
+		for i, value := range values {
+			slice[i] = value.AsInt64()
+		}
+		return attribute.Int64SliceValue(slice), true
+	case attribute.FLOAT64:
+		slice := make([]float64, len(values))
+		for i, value := range values {
+			slice[i] = value.AsFloat64()
+		}
+		return attribute.Float64SliceValue(slice), true
+	default:
+		return attribute.Value{}, false
+	}
+}
+
+func jsonString(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return string(encoded)
+}
+
+// RequestVariablesRedacted sets the paths of the redacted request variables.
+func RequestVariablesRedacted(paths []string) attribute.KeyValue {
+	return requestVariablesRedactedKey.StringSlice(paths)
+}
+
 // ResolverPath sets resolver path.
 func ResolverPath(resolverPath string) attribute.KeyValue {
 	return resolverPathKey.String(resolverPath)
@@ -84,6 +326,11 @@ func ResolverAlias(resolverAlias string) attribute.KeyValue {
 	return resolverAliasKey.String(resolverAlias)
 }
 
+// ResolverHasError sets whether the resolver produced errors.
+func ResolverHasError(hasError bool) attribute.KeyValue {
+	return resolverHasErrorKey.Bool(hasError)
+}
+
 // ResolverArgs sets resolver args.
 func ResolverArgs(argList ast.ArgumentList) []attribute.KeyValue {
 	args := make([]attribute.KeyValue, 0, len(argList))
@@ -97,6 +344,18 @@ func ResolverArgs(argList ast.ArgumentList) []attribute.KeyValue {
 	return args
 }
 
+// ResolvedResolverArgs sets resolver args from the values received by the resolver,
+// i.e. after variables substitution and defaulting, encoding each of them as JSON.
+func ResolvedResolverArgs(args map[string]interface{}) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(args))
+	for k, v := range args {
+		attrs = append(attrs,
+			attribute.String(fmt.Sprintf("%s.%s", resolverArgsPrefix, k), jsonString(v)),
+		)
+	}
+	return attrs
+}
+
 // ResolverErrors sets errors.
 func ResolverErrors(errorList gqlerror.List) []attribute.KeyValue {
 	errors := make([]attribute.KeyValue, 0, len(errorList)*4)
@@ -106,7 +365,7 @@ func ResolverErrors(errorList gqlerror.List) []attribute.KeyValue {
 			resolverHasErrorKey.Bool(true),
 			resolverErrorCountKey.Int64(int64(len(errorList))),
 			attribute.String(fmt.Sprintf("%s.%d.message", resolverErrorPrefix, idx), err.Error()),
-			attribute.String(fmt.Sprintf("%s.%d.kind", resolverErrorPrefix, idx), fmt.Sprintf("%T", err)),
+			attribute.String(fmt.Sprintf("%s.%d.kind", resolverErrorPrefix, idx), errorType(err)),
 		)
 	}
 
@@ -0,0 +1,94 @@
+// Copyright Ravil Galaktionov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelgqlgen
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestTypedRequestVariables(t *testing.T) {
+	variables := map[string]interface{}{
+		"id":      int64(1),
+		"ratio":   0.5,
+		"enabled": true,
+		"name":    "john",
+		"count":   json.Number("3"),
+		"tags":    []interface{}{"a", "b"},
+		"ids":     []interface{}{int64(1), int64(2)},
+		"mixed":   []interface{}{"a", int64(1)},
+		"input": map[string]interface{}{
+			"age": int64(42),
+			"address": map[string]interface{}{
+				"city": "Berlin",
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		maxDepth      int
+		maxAttributes int
+		expected      []attribute.KeyValue
+	}{
+		{
+			name: "no limits",
+			expected: []attribute.KeyValue{
+				attribute.Int64("gql.request.variables.count", 3),
+				attribute.Bool("gql.request.variables.enabled", true),
+				attribute.Int64("gql.request.variables.id", 1),
+				attribute.Int64Slice("gql.request.variables.ids", []int64{1, 2}),
+				attribute.String("gql.request.variables.input.address.city", "Berlin"),
+				attribute.Int64("gql.request.variables.input.age", 42),
+				attribute.String("gql.request.variables.mixed", `["a",1]`),
+				attribute.String("gql.request.variables.name", "john"),
+				attribute.Float64("gql.request.variables.ratio", 0.5),
+				attribute.StringSlice("gql.request.variables.tags", []string{"a", "b"}),
+			},
+		},
+		{
+			name:          "limits",
+			maxDepth:      2,
+			maxAttributes: 6,
+			expected: []attribute.KeyValue{
+				attribute.Int64("gql.request.variables.count", 3),
+				attribute.Bool("gql.request.variables.enabled", true),
+				attribute.Int64("gql.request.variables.id", 1),
+				attribute.Int64Slice("gql.request.variables.ids", []int64{1, 2}),
+				attribute.String("gql.request.variables.input.address", `{"city":"Berlin"}`),
+				attribute.Int64("gql.request.variables.input.age", 42),
+				attribute.Bool("gql.request.variables.truncated", true),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, TypedRequestVariables(tt.maxDepth, tt.maxAttributes)(variables))
+		})
+	}
+}
+
+func TestJSONRequestVariables(t *testing.T) {
+	variables := map[string]interface{}{
+		"input": map[string]interface{}{"age": int64(42), "tags": []interface{}{"a"}},
+	}
+
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("gql.request.variables.input", `{"age":42,"tags":["a"]}`),
+	}, JSONRequestVariables(variables))
+}